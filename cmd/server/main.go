@@ -3,20 +3,33 @@ package main
 import (
 	stdlog "log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	_ "time/tzdata"
 
+	"goworld-skeleton/internal/auth"
 	"goworld-skeleton/internal/config"
 	"goworld-skeleton/internal/dao"
+	"goworld-skeleton/internal/event"
 	logger "goworld-skeleton/internal/log"
 	"goworld-skeleton/internal/modules/account"
 	"goworld-skeleton/internal/modules/bag"
 	"goworld-skeleton/internal/modules/chat"
+	"goworld-skeleton/internal/modules/friend"
 	"goworld-skeleton/internal/modules/item"
+	"goworld-skeleton/internal/modules/leaderboard"
 	"goworld-skeleton/internal/modules/mail"
 	"goworld-skeleton/internal/modules/match"
 	"goworld-skeleton/internal/modules/notice"
 	"goworld-skeleton/internal/modules/player"
+	"goworld-skeleton/internal/modules/quest"
 	"goworld-skeleton/internal/modules/room"
 	"goworld-skeleton/internal/modules/shop"
+	"goworld-skeleton/internal/modules/trade"
+	"goworld-skeleton/internal/modules/wallet"
+	"goworld-skeleton/internal/presence"
 	"goworld-skeleton/internal/redis"
 	"goworld-skeleton/internal/server"
 )
@@ -25,19 +38,56 @@ func main() {
 	cfg := config.Default()
 	log := logger.New(cfg.Environment)
 	store := dao.NewDataStore()
+	if path := cfg.Snapshot.Path; path != "" {
+		if err := store.Load(path); err != nil {
+			stdlog.Fatalf("failed to restore data from %s: %v", path, err)
+		}
+		go runSnapshots(store, path, cfg.Snapshot.Interval, log)
+	}
 	cache := redis.NewCache()
+	events := event.NewBus()
+
+	secret := []byte(cfg.TokenSecret)
+	if len(secret) == 0 {
+		log.Printf("GOWORLD_TOKEN_SECRET not set, using an ephemeral signing key")
+		secret = auth.RandomSecret()
+	}
+	sessions := auth.NewManager(secret, cache, auth.Options{TTL: cfg.SessionTTL, SingleSession: cfg.SingleSession})
+
+	tracker := presence.NewTracker(cache, events, cfg.Presence.Timeout, cfg.Presence.Retention)
+	go tracker.Sweep(15 * time.Second)
+
+	boards := leaderboard.NewService(store, cache, cfg, log)
+	go boards.RunSnapshots()
+
+	accounts := account.NewService(store, cache, sessions, tracker, boards, cfg, events, log)
+	go accounts.SweepDeletions(time.Minute)
+
+	bags := bag.NewService(store, cfg.BagCapacity, log)
+	quests := quest.NewService(store, bags, cfg, events, log)
+	go quests.RunResets()
+
+	players := player.NewService(store, cache, sessions, cfg, events, tracker, boards, bags, log)
+	go players.SweepCharacters(time.Minute)
 
 	services := server.Services{
-		Account: account.NewService(store, cache, log),
-		Player:  player.NewService(store, log),
-		Bag:     bag.NewService(store, log),
-		Item:    item.NewService(store),
-		Shop:    shop.NewService(store, log),
-		Mail:    mail.NewService(store, log),
-		Notice:  notice.NewService(store),
-		Chat:    chat.NewService(store, log),
-		Room:    room.NewService(store, log),
-		Match:   match.NewService(store, log),
+		Sessions:    sessions,
+		AdminToken:  cfg.AdminToken,
+		Account:     accounts,
+		Player:      players,
+		Bag:         bags,
+		Item:        item.NewService(store),
+		Shop:        shop.NewService(store, log),
+		Mail:        mail.NewService(store, bags, log),
+		Notice:      notice.NewService(store),
+		Chat:        chat.NewService(store, events, log),
+		Room:        room.NewService(store, events, log),
+		Match:       match.NewService(store, events, boards, log),
+		Wallet:      wallet.NewService(store, log),
+		Friend:      friend.NewService(store, tracker, cfg.FriendLimit, log),
+		Quest:       quests,
+		Leaderboard: boards,
+		Trade:       trade.NewService(store, bags, cfg.TradeIdleTimeout, log),
 	}
 
 	handler := server.NewRouter(services)
@@ -46,3 +96,27 @@ func main() {
 		stdlog.Fatalf("failed to start server: %v", err)
 	}
 }
+
+// runSnapshots saves the store to path every interval and once more on
+// SIGINT or SIGTERM before exiting.
+func runSnapshots(store *dao.DataStore, path string, interval time.Duration, log *stdlog.Logger) {
+	save := func() {
+		if err := store.Save(path); err != nil {
+			log.Printf("snapshot %s: %v", path, err)
+		}
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			save()
+		case <-stop:
+			save()
+			os.Exit(0)
+		}
+	}
+}
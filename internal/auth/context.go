@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+var (
+	// ErrPlayerMismatch is returned when a request targets a player other
+	// than the session's selected character.
+	ErrPlayerMismatch = errors.New("player does not match session")
+	// ErrNoCharacter is returned for player routes before a character has
+	// been selected.
+	ErrNoCharacter = errors.New("no character selected")
+)
+
+type sessionContextKey struct{}
+type operatorContextKey struct{}
+
+// WithSession returns a context carrying the authenticated session.
+func WithSession(ctx context.Context, session Session) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, session)
+}
+
+// SessionFrom returns the authenticated session stored on the context.
+func SessionFrom(ctx context.Context) (Session, bool) {
+	session, ok := ctx.Value(sessionContextKey{}).(Session)
+	return session, ok
+}
+
+// WithOperator returns a context carrying the name of the ops user behind an
+// admin request.
+func WithOperator(ctx context.Context, operator string) context.Context {
+	return context.WithValue(ctx, operatorContextKey{}, operator)
+}
+
+// Operator returns the ops user behind an admin request, or "".
+func Operator(r *http.Request) string {
+	operator, _ := r.Context().Value(operatorContextKey{}).(string)
+	return operator
+}
+
+// AccountID returns the authenticated account for the request, or "" if the
+// request was not authenticated.
+func AccountID(r *http.Request) string {
+	session, _ := SessionFrom(r.Context())
+	return session.AccountID
+}
+
+// PlayerID returns the character selected in the request's session, or ""
+// if there is none.
+func PlayerID(r *http.Request) string {
+	session, _ := SessionFrom(r.Context())
+	return session.CharacterID
+}
+
+// ResolvePlayer checks a client-supplied player ID against the session's
+// selected character. An empty ID or "me" resolves to that character.
+func ResolvePlayer(r *http.Request, requested string) (string, error) {
+	session, ok := SessionFrom(r.Context())
+	if !ok {
+		return "", ErrInvalidToken
+	}
+	playerID := session.CharacterID
+	if playerID == "" {
+		return "", ErrNoCharacter
+	}
+	if requested != "" && requested != "me" && requested != playerID {
+		return "", ErrPlayerMismatch
+	}
+	return playerID, nil
+}
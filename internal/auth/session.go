@@ -0,0 +1,297 @@
+package auth
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"goworld-skeleton/internal/redis"
+)
+
+const (
+	sessionKeyPrefix = "session:"
+	revokedKeyPrefix = "revoked:"
+
+	// lastSeenResolution limits how often Verify rewrites a session record.
+	lastSeenResolution = time.Minute
+)
+
+// ErrSessionNotFound is returned when kicking a session that does not exist.
+var ErrSessionNotFound = errors.New("session not found")
+
+// Session is the server-side record behind an issued token. CharacterID is
+// the player the session currently acts as.
+type Session struct {
+	ID          string    `json:"id"`
+	AccountID   string    `json:"account_id"`
+	CharacterID string    `json:"character_id"`
+	Device      string    `json:"device"`
+	IP          string    `json:"ip"`
+	IssuedAt    time.Time `json:"issued_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// Client describes the device a session is started from.
+type Client struct {
+	Device string
+	IP     string
+}
+
+// ClientFromRequest extracts the device label and remote IP of a request.
+// The device falls back to the User-Agent when the client does not name one.
+func ClientFromRequest(r *http.Request, device string) Client {
+	if device == "" {
+		device = r.UserAgent()
+	}
+	return Client{Device: device, IP: ClientIP(r)}
+}
+
+// ClientIP returns the remote address of the request without its port.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Options tunes session issuance.
+type Options struct {
+	TTL time.Duration
+	// SingleSession revokes an account's other sessions whenever a new one
+	// is issued.
+	SingleSession bool
+}
+
+// Manager issues, verifies and revokes session tokens backed by the cache.
+// Sessions live under session:<accountID>:<sessionID> so an account's
+// devices can be listed with a prefix scan.
+type Manager struct {
+	signer Signer
+	cache  *redis.Cache
+	opts   Options
+	// mu serializes changes to stored sessions, so an update can neither
+	// undo another one nor bring back a revoked session.
+	mu sync.Mutex
+}
+
+// NewManager constructs a session manager.
+func NewManager(secret []byte, cache *redis.Cache, opts Options) *Manager {
+	return &Manager{signer: NewSigner(secret), cache: cache, opts: opts}
+}
+
+// Issue starts a new session for the account, acting as characterID (which
+// may be empty), and returns its signed token.
+func (m *Manager) Issue(accountID, characterID string, client Client) (string, Session, error) {
+	id, err := newSessionID()
+	if err != nil {
+		return "", Session{}, err
+	}
+
+	now := time.Now()
+	session := Session{
+		ID:          id,
+		AccountID:   accountID,
+		CharacterID: characterID,
+		Device:      client.Device,
+		IP:          client.IP,
+		IssuedAt:    now,
+		ExpiresAt:   now.Add(m.opts.TTL),
+		LastSeen:    now,
+	}
+	token, err := m.signer.Sign(Claims{
+		AccountID: accountID,
+		SessionID: id,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: session.ExpiresAt.Unix(),
+	})
+	if err != nil {
+		return "", Session{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.opts.SingleSession {
+		m.revokeAll(accountID)
+	}
+	m.save(session)
+	return token, session, nil
+}
+
+// Verify checks the token signature and expiry, then confirms the session
+// is still live and has not been revoked.
+func (m *Manager) Verify(token string) (Session, error) {
+	claims, err := m.signer.Parse(token, time.Now())
+	if err != nil {
+		return Session{}, err
+	}
+
+	if _, revoked := m.cache.Get(revokedKeyPrefix + claims.SessionID); revoked {
+		return Session{}, ErrRevokedToken
+	}
+
+	session, ok := m.load(sessionKey(claims.AccountID, claims.SessionID))
+	if !ok {
+		return Session{}, ErrRevokedToken
+	}
+
+	if now := time.Now(); now.Sub(session.LastSeen) >= lastSeenResolution {
+		if updated, ok := m.update(session, func(s *Session) { s.LastSeen = now }); ok {
+			session = updated
+		}
+	}
+	return session, nil
+}
+
+// Refresh exchanges a valid token for a new one on the same device and
+// revokes the old session. Each token refreshes at most once, even when
+// raced. The new session records client's IP, and its device name when the
+// old session had none.
+func (m *Manager) Refresh(token string, client Client) (string, Session, error) {
+	current, err := m.Verify(token)
+	if err != nil {
+		return "", Session{}, err
+	}
+	if !m.revokeIfLive(current) {
+		return "", Session{}, ErrRevokedToken
+	}
+
+	if current.Device != "" {
+		client.Device = current.Device
+	}
+	return m.Issue(current.AccountID, current.CharacterID, client)
+}
+
+// Select switches the session to act as another character.
+func (m *Manager) Select(session Session, characterID string) (Session, error) {
+	current, ok := m.update(session, func(s *Session) { s.CharacterID = characterID })
+	if !ok {
+		return Session{}, ErrSessionNotFound
+	}
+	return current, nil
+}
+
+// Deselect clears characterID from every session of the account that is
+// acting as it, e.g. after the character is deleted.
+func (m *Manager) Deselect(accountID, characterID string) {
+	for _, session := range m.List(accountID) {
+		if session.CharacterID == characterID {
+			m.update(session, func(s *Session) {
+				if s.CharacterID == characterID {
+					s.CharacterID = ""
+				}
+			})
+		}
+	}
+}
+
+// List returns the account's live sessions, most recently seen first.
+func (m *Manager) List(accountID string) []Session {
+	sessions := make([]Session, 0)
+	for _, key := range m.cache.Keys(sessionKey(accountID, "")) {
+		if session, ok := m.load(key); ok {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastSeen.After(sessions[j].LastSeen) })
+	return sessions
+}
+
+// Kick revokes one of the account's sessions.
+func (m *Manager) Kick(accountID, sessionID string) error {
+	session, ok := m.load(sessionKey(accountID, sessionID))
+	if !ok {
+		return ErrSessionNotFound
+	}
+	m.Revoke(session)
+	return nil
+}
+
+// RevokeAll revokes every live session of the account and returns how many
+// were revoked.
+func (m *Manager) RevokeAll(accountID string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.revokeAll(accountID)
+}
+
+// revokeAll is RevokeAll for callers that hold m.mu.
+func (m *Manager) revokeAll(accountID string) int {
+	sessions := m.List(accountID)
+	for _, session := range sessions {
+		m.revoke(session)
+	}
+	return len(sessions)
+}
+
+// Revoke invalidates a session until its token would have expired anyway.
+func (m *Manager) Revoke(session Session) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.revoke(session)
+}
+
+// revokeIfLive revokes session and reports whether it was still live, so
+// only one of several callers racing on it wins.
+func (m *Manager) revokeIfLive(session Session) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.load(sessionKey(session.AccountID, session.ID)); !ok {
+		return false
+	}
+	if _, revoked := m.cache.Get(revokedKeyPrefix + session.ID); revoked {
+		return false
+	}
+	m.revoke(session)
+	return true
+}
+
+// revoke is Revoke for callers that hold m.mu.
+func (m *Manager) revoke(session Session) {
+	remaining := time.Until(session.ExpiresAt)
+	if remaining > 0 {
+		m.cache.Set(revokedKeyPrefix+session.ID, session.AccountID, remaining)
+	}
+	m.cache.Delete(sessionKey(session.AccountID, session.ID))
+}
+
+// update applies change to the stored copy of session and saves it. It
+// reports false, saving nothing, if the session is gone or revoked.
+func (m *Manager) update(session Session, change func(*Session)) (Session, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	current, ok := m.load(sessionKey(session.AccountID, session.ID))
+	if !ok {
+		return Session{}, false
+	}
+	if _, revoked := m.cache.Get(revokedKeyPrefix + session.ID); revoked {
+		return Session{}, false
+	}
+	change(&current)
+	m.save(current)
+	return current, true
+}
+
+func (m *Manager) save(session Session) {
+	m.cache.Set(sessionKey(session.AccountID, session.ID), session, time.Until(session.ExpiresAt))
+}
+
+func (m *Manager) load(key string) (Session, bool) {
+	value, ok := m.cache.Get(key)
+	if !ok {
+		return Session{}, false
+	}
+	session, ok := value.(Session)
+	return session, ok
+}
+
+// sessionKey escapes the account ID so that one account's prefix can never
+// match another's.
+func sessionKey(accountID, sessionID string) string {
+	return sessionKeyPrefix + url.QueryEscape(accountID) + ":" + sessionID
+}
@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"goworld-skeleton/internal/redis"
+)
+
+var testSecret = []byte("test-secret")
+
+func newTestManager(opts Options) *Manager {
+	if opts.TTL == 0 {
+		opts.TTL = time.Hour
+	}
+	return NewManager(testSecret, redis.NewCache(), opts)
+}
+
+func TestVerifyRejects(t *testing.T) {
+	m := newTestManager(Options{})
+	valid, session, err := m.Issue("acct", "char", Client{Device: "phone", IP: "10.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, revokedSession, err := m.Issue("acct", "char", Client{Device: "tablet"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Revoke(revokedSession)
+
+	now := time.Now()
+	expired, err := m.signer.Sign(Claims{AccountID: "acct", SessionID: session.ID, IssuedAt: now.Add(-2 * time.Hour).Unix(), ExpiresAt: now.Add(-time.Hour).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := NewSigner([]byte("other-secret")).Sign(Claims{AccountID: "acct", SessionID: session.ID, ExpiresAt: now.Add(time.Hour).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknown, err := m.signer.Sign(Claims{AccountID: "acct", SessionID: "never-issued", ExpiresAt: now.Add(time.Hour).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(valid, ".")
+	otherClaims := strings.Split(unknown, ".")
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{name: "valid", token: valid},
+		{name: "empty", token: "", want: ErrInvalidToken},
+		{name: "wrong version", token: "v0." + parts[1] + "." + parts[2], want: ErrInvalidToken},
+		{name: "swapped payload", token: parts[0] + "." + otherClaims[1] + "." + parts[2], want: ErrInvalidToken},
+		{name: "truncated signature", token: valid[:len(valid)-2], want: ErrInvalidToken},
+		{name: "signed with another secret", token: forged, want: ErrInvalidToken},
+		{name: "expired", token: expired, want: ErrExpiredToken},
+		{name: "revoked", token: revoked, want: ErrRevokedToken},
+		{name: "unknown session", token: unknown, want: ErrRevokedToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := m.Verify(tt.token)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Verify error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	m := newTestManager(Options{})
+	token, old, err := m.Issue("acct", "char", Client{Device: "phone", IP: "10.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fresh, session, err := m.Refresh(token, Client{Device: "curl", IP: "10.0.0.2"})
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if session.ID == old.ID || session.CharacterID != "char" || session.Device != "phone" || session.IP != "10.0.0.2" {
+		t.Errorf("refreshed session = %+v", session)
+	}
+	if _, err := m.Verify(token); !errors.Is(err, ErrRevokedToken) {
+		t.Errorf("old token verifies with %v, want %v", err, ErrRevokedToken)
+	}
+	if _, err := m.Verify(fresh); err != nil {
+		t.Errorf("new token: %v", err)
+	}
+	if _, _, err := m.Refresh(token, Client{}); !errors.Is(err, ErrRevokedToken) {
+		t.Errorf("second refresh error = %v, want %v", err, ErrRevokedToken)
+	}
+}
+
+func TestConcurrentRefreshForksOnce(t *testing.T) {
+	m := newTestManager(Options{})
+	token, _, err := m.Issue("acct", "", Client{Device: "phone"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const racers = 64
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < racers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if _, _, err := m.Refresh(token, Client{}); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Errorf("%d refreshes succeeded, want 1", succeeded)
+	}
+	if live := m.List("acct"); len(live) != 1 {
+		t.Errorf("%d live sessions, want 1", len(live))
+	}
+}
+
+func TestRevokeIfLive(t *testing.T) {
+	m := newTestManager(Options{})
+	_, session, err := m.Issue("acct", "", Client{Device: "phone"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, kicked, err := m.Issue("acct", "", Client{Device: "tablet"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Revoke(kicked)
+
+	// Both refreshes of a raced token have passed Verify; only the first
+	// may go on to issue a new session.
+	if !m.revokeIfLive(session) {
+		t.Error("first revokeIfLive = false, want true")
+	}
+	if m.revokeIfLive(session) {
+		t.Error("second revokeIfLive = true, want false")
+	}
+	if m.revokeIfLive(kicked) {
+		t.Error("revokeIfLive on a revoked session = true, want false")
+	}
+}
+
+func TestSingleSessionConcurrentLogins(t *testing.T) {
+	m := newTestManager(Options{SingleSession: true})
+	if _, _, err := m.Issue("acct", "", Client{Device: "old"}); err != nil {
+		t.Fatal(err)
+	}
+
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if _, _, err := m.Issue("acct", "", Client{Device: "new"}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if live := m.List("acct"); len(live) != 1 {
+		t.Errorf("%d live sessions, want 1", len(live))
+	}
+}
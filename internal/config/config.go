@@ -1,15 +1,150 @@
 package config
 
+import (
+	"os"
+	"time"
+)
+
 // Config holds service-level configuration.
 type Config struct {
 	HTTPPort    string
 	Environment string
+	TokenSecret string
+	SessionTTL  time.Duration
+	// SingleSession makes a new login invalidate the account's older sessions.
+	SingleSession bool
+	// AdminToken guards the /api/admin/ routes; they are disabled when empty.
+	AdminToken string
+	// LoginThrottle limits failed password attempts.
+	LoginThrottle LoginThrottle
+	// Characters limits character slots per account.
+	Characters Characters
+	// Names sets the rules for character names.
+	Names Names
+	// DailyReset sets when a new game day starts for daily logins.
+	DailyReset DailyReset
+	// BagCapacity is the number of bag slots per player.
+	BagCapacity int
+	// Snapshot sets where player data is saved so it survives restarts.
+	Snapshot Snapshot
+	// FriendLimit caps how many friends a player can have.
+	FriendLimit int
+	// TradeIdleTimeout is how long an open trade may go without any change
+	// before it is abandoned. Zero keeps trades open until cancelled.
+	TradeIdleTimeout time.Duration
+	// LeaderboardSnapshotSize is how many top entries are kept when a board
+	// is snapshotted at the end of each game week.
+	LeaderboardSnapshotSize int
+	// Presence sets how presence records time out.
+	Presence Presence
+	// DeletionCooldown is how long a requested account deletion can still be
+	// cancelled before the data is removed.
+	DeletionCooldown time.Duration
+}
+
+// Snapshot saves every player-owned table to Path every Interval and on
+// shutdown, and restores them at startup. An empty Path, the default,
+// disables it and keeps all data in memory.
+type Snapshot struct {
+	Path     string
+	Interval time.Duration
+}
+
+// Presence configures online tracking. A player without a heartbeat for
+// Timeout is offline; last-seen records are kept for Retention.
+type Presence struct {
+	Timeout   time.Duration
+	Retention time.Duration
+}
+
+// DailyReset places the game-day boundary at Hour o'clock in Timezone, an
+// IANA zone name.
+type DailyReset struct {
+	Hour     int
+	Timezone string
+}
+
+// Names configures character name validation. Reserved words may not be
+// used as a whole name; banned words may not appear anywhere in one. Both
+// are matched case-insensitively.
+type Names struct {
+	MinLength      int
+	MaxLength      int
+	Reserved       []string
+	Banned         []string
+	RenameCooldown time.Duration
+}
+
+// Characters configures character slots. Deleted characters keep their slot
+// and can be restored until DeleteGrace has passed.
+type Characters struct {
+	Slots       int
+	DeleteGrace time.Duration
+}
+
+// LoginThrottle configures brute-force protection on login. Failures are
+// counted per username and per client IP; once a counter reaches its
+// threshold every further failure locks the key out for BaseLockout,
+// doubling each time up to MaxLockout. A successful login resets both
+// counters, unless KeepIPFailures is set: then the IP counter only expires
+// with its Window, so an attacker cannot reset their IP budget by logging
+// into an account of their own between guesses. Guest logins share the
+// per-IP lockout, and an IP may create at most MaxGuestsPerIP guest accounts
+// per Window.
+type LoginThrottle struct {
+	MaxFailuresPerUser int
+	MaxFailuresPerIP   int
+	KeepIPFailures     bool
+	MaxGuestsPerIP     int
+	Window             time.Duration
+	BaseLockout        time.Duration
+	MaxLockout         time.Duration
 }
 
 // Default returns sensible defaults for local development and demos.
 func Default() Config {
 	return Config{
-		HTTPPort:    ":8080",
-		Environment: "development",
+		HTTPPort:      ":8080",
+		Environment:   "development",
+		TokenSecret:   os.Getenv("GOWORLD_TOKEN_SECRET"),
+		SessionTTL:    30 * time.Minute,
+		SingleSession: os.Getenv("GOWORLD_SINGLE_SESSION") == "true",
+		AdminToken:    os.Getenv("GOWORLD_ADMIN_TOKEN"),
+		LoginThrottle: LoginThrottle{
+			MaxFailuresPerUser: 5,
+			MaxFailuresPerIP:   20,
+			MaxGuestsPerIP:     10,
+			Window:             15 * time.Minute,
+			BaseLockout:        30 * time.Second,
+			MaxLockout:         time.Hour,
+		},
+		Characters: Characters{
+			Slots:       4,
+			DeleteGrace: 72 * time.Hour,
+		},
+		Names: Names{
+			MinLength:      2,
+			MaxLength:      16,
+			Reserved:       []string{"admin", "administrator", "gm", "system", "official", "support", "server", "deleted-player", "管理员", "客服", "系统", "官方"},
+			Banned:         []string{"fuck", "shit", "bitch", "傻逼", "操你"},
+			RenameCooldown: 7 * 24 * time.Hour,
+		},
+		DailyReset: DailyReset{
+			Hour:     5,
+			Timezone: "Asia/Shanghai",
+		},
+		BagCapacity:             40,
+		FriendLimit:             100,
+		LeaderboardSnapshotSize: 100,
+		Snapshot: Snapshot{
+			Path:     os.Getenv("GOWORLD_SNAPSHOT"),
+			Interval: 30 * time.Second,
+		},
+		Presence: Presence{
+			Timeout:   90 * time.Second,
+			Retention: 30 * 24 * time.Hour,
+		},
+		TradeIdleTimeout: 5 * time.Minute,
+		DeletionCooldown: 7 * 24 * time.Hour,
 	}
 }
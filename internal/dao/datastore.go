@@ -10,11 +10,52 @@ type DataStore struct {
 	mu sync.RWMutex
 
 	Accounts map[string]Account
-	Players  map[string]Player
-	Items    []Item
-	Notices  []Notice
-	Mails    map[string][]Mail
-	Bags     map[string][]BagEntry
+	// Credentials maps a login credential such as "username:demo" or
+	// "device:<id>" to the owning account ID.
+	Credentials map[string]string
+	// Players holds characters keyed by character ID; an account may own
+	// several.
+	Players map[string]Player
+	// NameHistory keeps every rename, per player, oldest first.
+	NameHistory map[string][]NameChange
+	// Bans keeps every ban ever issued, per account, oldest first.
+	Bans  map[string][]Ban
+	Items []Item
+	// Levels is the level curve, ordered by level; the last entry is the cap.
+	Levels  []LevelDef
+	Notices []Notice
+	Mails   map[string][]Mail
+	// Bags holds each player's stacks ordered by slot. Writers replace the
+	// slice rather than mutate it.
+	Bags map[string][]BagEntry
+	// BagRevisions counts the changes to each bag, so clients acting on
+	// slot indices can detect a stale layout.
+	BagRevisions map[string]int64
+	// Buffs holds timed stat modifiers per player.
+	Buffs map[string][]Buff
+	// Ledger is the append-only record of every wallet change.
+	Ledger []LedgerEntry
+	// LoginCalendar lists daily login rewards by consecutive day; the
+	// calendar repeats once a streak runs past its end.
+	LoginCalendar []LoginReward
+	// Quests defines daily and weekly quests and lifetime achievements.
+	Quests []QuestDef
+	// QuestProgress tracks quest progress per player, keyed by quest ID.
+	QuestProgress map[string]map[string]QuestProgress
+	// LeaderboardSnapshots keeps the final standings of every board at the
+	// end of each period, oldest first.
+	LeaderboardSnapshots []LeaderboardSnapshot
+	// Friends lists each player's friends; a friendship appears under both
+	// players.
+	Friends map[string][]Friend
+	// FriendRequests holds pending requests keyed by recipient.
+	FriendRequests map[string][]FriendRequest
+	// Blocks lists the players each player has blocked.
+	Blocks map[string][]string
+	// Trades holds the open trade sessions by ID; TradeLog keeps every
+	// completed trade, oldest first, for fraud review.
+	Trades   map[string]Trade
+	TradeLog []TradeRecord
 	Chats    []ChatMessage
 	Rooms    map[string]Room
 }
@@ -22,25 +63,104 @@ type DataStore struct {
 // NewDataStore seeds a datastore with demo data.
 func NewDataStore() *DataStore {
 	items := []Item{
-		{ID: "potion", Name: "Small Potion", Rarity: "common", Price: 25},
-		{ID: "sword", Name: "Bronze Sword", Rarity: "uncommon", Price: 120},
+		{ID: "potion", Name: "Small Potion", Rarity: "common", Price: 25, MaxStack: 99, Use: &ItemEffect{Type: EffectHeal, Amount: 50}},
+		{ID: "sword", Name: "Bronze Sword", Rarity: "uncommon", Price: 120, MaxStack: 1, Slot: SlotWeapon, Stats: Stats{Attack: 12}},
+		{ID: "leather_armor", Name: "Leather Armor", Rarity: "common", Price: 90, MaxStack: 1, Slot: SlotArmor, Level: 3, Stats: Stats{HP: 40, Defense: 8}},
+		{ID: "jade_ring", Name: "Jade Ring", Rarity: "rare", Price: 300, MaxStack: 1, Slot: SlotAccessory, Level: 5, Stats: Stats{Speed: 6}},
+		{ID: "exp_scroll", Name: "Scroll of Insight", Rarity: "uncommon", Price: 80, MaxStack: 20, Bound: true, Use: &ItemEffect{Type: EffectGrantExp, Amount: 200}},
+		{ID: "gold_pouch", Name: "Gold Pouch", Rarity: "common", Price: 100, MaxStack: 50, Use: &ItemEffect{Type: EffectGrantCurrency, Currency: CurrencyGold, Amount: 100}},
+		{ID: "lucky_box", Name: "Lucky Box", Rarity: "rare", Price: 150, MaxStack: 10, Use: &ItemEffect{Type: EffectLootBox, Loot: []LootDrop{
+			{ItemID: "potion", Quantity: 5, Weight: 60},
+			{ItemID: "gold_pouch", Quantity: 1, Weight: 30},
+			{ItemID: "sword", Quantity: 1, Weight: 10},
+		}}},
+		{ID: "attack_elixir", Name: "Elixir of Might", Rarity: "uncommon", Price: 60, MaxStack: 20, Use: &ItemEffect{Type: EffectBuff, Stats: Stats{Attack: 10}, Seconds: 1800}},
+	}
+
+	levels := []LevelDef{
+		{Level: 1, Experience: 0},
+		{Level: 2, Experience: 100},
+		{Level: 3, Experience: 250},
+		{Level: 4, Experience: 450},
+		{Level: 5, Experience: 700, Rewards: []MailAttachment{{ItemID: "potion", Quantity: 5}}, Delivery: RewardToMail},
+		{Level: 6, Experience: 1000},
+		{Level: 7, Experience: 1300},
+		{Level: 8, Experience: 1600},
+		{Level: 9, Experience: 1900},
+		{Level: 10, Experience: 2200, Rewards: []MailAttachment{{ItemID: "sword", Quantity: 1}}, Delivery: RewardToMail},
+		{Level: 11, Experience: 2600},
+		{Level: 12, Experience: 3050},
+		{Level: 13, Experience: 3550},
+		{Level: 14, Experience: 4100},
+		{Level: 15, Experience: 4700, Rewards: []MailAttachment{{ItemID: "potion", Quantity: 10}}, Delivery: RewardToBag},
+		{Level: 16, Experience: 5350},
+		{Level: 17, Experience: 6050},
+		{Level: 18, Experience: 6800},
+		{Level: 19, Experience: 7600},
+		{Level: 20, Experience: 8500, Rewards: []MailAttachment{{ItemID: "sword", Quantity: 1}}, Delivery: RewardToMail},
+	}
+
+	for i := range levels {
+		step := levels[i].Level - 1
+		levels[i].Stats = Stats{HP: 100 + 20*step, Attack: 10 + 3*step, Defense: 5 + 2*step, Speed: 10 + step/2}
+	}
+
+	loginCalendar := []LoginReward{
+		{Day: 1, Currency: map[string]int64{CurrencyGold: 100}},
+		{Day: 2, Items: []MailAttachment{{ItemID: "potion", Quantity: 2}}},
+		{Day: 3, Currency: map[string]int64{CurrencyGold: 200}},
+		{Day: 4, Items: []MailAttachment{{ItemID: "potion", Quantity: 3}}},
+		{Day: 5, Currency: map[string]int64{CurrencyGold: 300}},
+		{Day: 6, Items: []MailAttachment{{ItemID: "potion", Quantity: 5}}},
+		{Day: 7, Currency: map[string]int64{CurrencyDiamond: 20}},
+	}
+
+	quests := []QuestDef{
+		{ID: "daily_login", Name: "每日登录", Period: QuestDaily, Condition: ConditionLoginDays, Target: 1, Currency: map[string]int64{CurrencyGold: 50}},
+		{ID: "daily_chat", Name: "畅所欲言", Period: QuestDaily, Condition: ConditionChatMessages, Target: 10, Currency: map[string]int64{CurrencyGold: 100}},
+		{ID: "daily_doudizhu_win", Name: "斗地主首胜", Period: QuestDaily, Condition: ConditionMatchWins, Mode: "doudizhu", Target: 1, Currency: map[string]int64{CurrencyDiamond: 5}},
+		{ID: "weekly_login", Name: "本周登录 3 天", Period: QuestWeekly, Condition: ConditionLoginDays, Target: 3, Items: []MailAttachment{{ItemID: "potion", Quantity: 3}}, Currency: map[string]int64{CurrencyDiamond: 10}},
+		{ID: "weekly_doudizhu", Name: "斗地主周常", Period: QuestWeekly, Condition: ConditionMatchesPlayed, Mode: "doudizhu", Target: 5, Currency: map[string]int64{CurrencyGold: 500}},
+		{ID: "ach_first_doudizhu_win", Name: "地主之王", Period: QuestLifetime, Condition: ConditionMatchWins, Mode: "doudizhu", Target: 1, Currency: map[string]int64{CurrencyDiamond: 20}},
+		{ID: "ach_level_10", Name: "初出茅庐", Period: QuestLifetime, Condition: ConditionLevel, Target: 10, Items: []MailAttachment{{ItemID: "sword", Quantity: 1}}},
+		{ID: "ach_login_30", Name: "忠实玩家", Period: QuestLifetime, Condition: ConditionLoginDays, Target: 30, Currency: map[string]int64{CurrencyDiamond: 100}},
 	}
 
 	notices := []Notice{{ID: "welcome", Title: "Welcome", Body: "服务器已启动，祝你游戏愉快！", Severity: "info", CreatedAt: time.Now()}}
 
 	players := map[string]Player{
-		"demo": {ID: "demo", Name: "DemoPlayer", Level: 10, Experience: 2200, LastLogin: time.Now()},
+		"demo": {ID: "demo", AccountID: "demo", Name: "DemoPlayer", Level: 10, Experience: 2200, LastLogin: time.Now(), CreatedAt: time.Now(), Wallet: map[string]int64{CurrencyGold: 1000, CurrencyDiamond: 50}},
+	}
+
+	ledger := []LedgerEntry{
+		{ID: "L1", PlayerID: "demo", Currency: CurrencyGold, Delta: 1000, Balance: 1000, Reason: "demo seed", Source: "dao", At: time.Now()},
+		{ID: "L2", PlayerID: "demo", Currency: CurrencyDiamond, Delta: 50, Balance: 50, Reason: "demo seed", Source: "dao", At: time.Now()},
 	}
 
 	return &DataStore{
-		Accounts: map[string]Account{"demo": {ID: "demo", Username: "demo", Password: "password", Token: "demo-token"}},
-		Players:  players,
-		Items:    items,
-		Notices:  notices,
-		Mails:    map[string][]Mail{"demo": {{ID: "m1", Subject: "欢迎礼包", Body: "感谢试玩", Attachments: []MailAttachment{{ItemID: "potion", Quantity: 3}}}}},
-		Bags:     map[string][]BagEntry{"demo": {{ItemID: "potion", Quantity: 2}}},
-		Chats:    []ChatMessage{},
-		Rooms:    map[string]Room{},
+		Accounts:             map[string]Account{"demo": {ID: "demo", Username: "demo", Password: "password"}},
+		Credentials:          map[string]string{"username:demo": "demo"},
+		Players:              players,
+		Bans:                 map[string][]Ban{},
+		NameHistory:          map[string][]NameChange{},
+		Items:                items,
+		Levels:               levels,
+		Notices:              notices,
+		Mails:                map[string][]Mail{"demo": {{ID: "m1", Subject: "欢迎礼包", Body: "感谢试玩", Attachments: []MailAttachment{{ItemID: "potion", Quantity: 3}}}}},
+		Bags:                 map[string][]BagEntry{"demo": {{Slot: 0, ItemID: "potion", Quantity: 2}}},
+		BagRevisions:         map[string]int64{},
+		Buffs:                map[string][]Buff{},
+		Ledger:               ledger,
+		LoginCalendar:        loginCalendar,
+		Quests:               quests,
+		QuestProgress:        map[string]map[string]QuestProgress{},
+		LeaderboardSnapshots: []LeaderboardSnapshot{},
+		Friends:              map[string][]Friend{},
+		FriendRequests:       map[string][]FriendRequest{},
+		Blocks:               map[string][]string{},
+		Trades:               map[string]Trade{},
+		Chats:                []ChatMessage{},
+		Rooms:                map[string]Room{},
 	}
 }
 
@@ -61,18 +181,225 @@ type Account struct {
 	ID       string `json:"id"`
 	Username string `json:"username"`
 	Password string `json:"-"`
-	Token    string `json:"token"`
+	Guest    bool   `json:"guest"`
+	// DeletionScheduledAt is set while a requested deletion is in its
+	// cooldown; the account is erased once it passes.
+	DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+}
+
+// Ban suspends an account. A zero ExpiresAt means the ban is permanent.
+type Ban struct {
+	ID        string    `json:"id"`
+	AccountID string    `json:"account_id"`
+	Reason    string    `json:"reason"`
+	Operator  string    `json:"operator"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	LiftedAt  time.Time `json:"lifted_at"`
+	LiftedBy  string    `json:"lifted_by,omitempty"`
 }
 
+// ActiveAt reports whether the ban is in force at t.
+func (b Ban) ActiveAt(t time.Time) bool {
+	if !b.LiftedAt.IsZero() {
+		return false
+	}
+	return b.ExpiresAt.IsZero() || t.Before(b.ExpiresAt)
+}
+
+// Player is a character owned by an account. A non-zero DeletedAt marks a
+// soft-deleted character that can still be restored until PurgeAt.
 type Player struct {
 	ID         string    `json:"id"`
+	AccountID  string    `json:"account_id"`
 	Name       string    `json:"name"`
 	Level      int       `json:"level"`
 	Experience int       `json:"experience"`
 	LastLogin  time.Time `json:"last_login"`
+	CreatedAt  time.Time `json:"created_at"`
+	DeletedAt  time.Time `json:"deleted_at"`
+	PurgeAt    time.Time `json:"purge_at"`
+	RenamedAt  time.Time `json:"renamed_at"`
+	// Equipment maps an equipment slot to the item ID worn in it. Writers
+	// replace the map rather than mutate it, like Wallet.
+	Equipment map[string]string `json:"equipment"`
+	// Damage is the HP the player has lost; zero means full health.
+	Damage int `json:"damage"`
+	// LoginDay is the game day of the last login; LoginStreak counts
+	// consecutive game days and LoginDays all distinct ones.
+	LoginDay    string `json:"login_day"`
+	LoginStreak int    `json:"login_streak"`
+	LoginDays   int    `json:"login_days"`
+	// Wallet holds currency balances. Writers replace the map rather than
+	// mutate it, so copies read outside the store lock stay consistent.
+	Wallet map[string]int64 `json:"wallet"`
+}
+
+// LoginReward is the reward for one day of the login calendar. Items arrive
+// by mail and currency goes straight to the wallet.
+type LoginReward struct {
+	Day      int              `json:"day"`
+	Items    []MailAttachment `json:"items,omitempty"`
+	Currency map[string]int64 `json:"currency,omitempty"`
+}
+
+// Quest periods. Daily and weekly progress resets with the game day or week;
+// lifetime quests are achievements and never reset.
+const (
+	QuestDaily    = "daily"
+	QuestWeekly   = "weekly"
+	QuestLifetime = "lifetime"
+)
+
+// Quest conditions. Counters add one per matching event; ConditionLevel
+// tracks the highest level reached.
+const (
+	ConditionLoginDays     = "login_days"
+	ConditionChatMessages  = "chat_messages"
+	ConditionMatchesPlayed = "matches_played"
+	ConditionMatchWins     = "match_wins"
+	ConditionLevel         = "level"
+)
+
+// QuestDef is a data-defined quest or achievement. Mode, when set, limits
+// match conditions to one game mode. Reward items arrive by mail and currency
+// goes straight to the wallet.
+type QuestDef struct {
+	ID        string           `json:"id"`
+	Name      string           `json:"name"`
+	Period    string           `json:"period"`
+	Condition string           `json:"condition"`
+	Mode      string           `json:"mode,omitempty"`
+	Target    int              `json:"target"`
+	Items     []MailAttachment `json:"items,omitempty"`
+	Currency  map[string]int64 `json:"currency,omitempty"`
+}
+
+// QuestProgress is a player's progress on one quest. Cycle names the game
+// day or week it counts toward and is empty for lifetime quests.
+type QuestProgress struct {
+	QuestID     string    `json:"quest_id"`
+	Cycle       string    `json:"cycle,omitempty"`
+	Progress    int       `json:"progress"`
+	CompletedAt time.Time `json:"completed_at"`
+	ClaimedAt   time.Time `json:"claimed_at"`
+}
+
+// RankEntry is one row of a leaderboard.
+type RankEntry struct {
+	Rank     int     `json:"rank"`
+	PlayerID string  `json:"player_id"`
+	Name     string  `json:"name"`
+	Level    int     `json:"level"`
+	Score    float64 `json:"score"`
+}
+
+// LeaderboardSnapshot freezes the top of a board at the end of Period.
+type LeaderboardSnapshot struct {
+	Board   string      `json:"board"`
+	Mode    string      `json:"mode,omitempty"`
+	Period  string      `json:"period"`
+	TakenAt time.Time   `json:"taken_at"`
+	Entries []RankEntry `json:"entries"`
+}
+
+// TradeOffer is what one player puts up in a trade.
+type TradeOffer struct {
+	PlayerID string           `json:"player_id"`
+	Items    []MailAttachment `json:"items"`
+	Currency map[string]int64 `json:"currency,omitempty"`
+}
+
+// Trade is an open trade session. Index 0 of each array is the player who
+// opened it, and nothing can be offered until the other player accepts.
+// Both sides lock their offer, then both confirm; changing an offer clears
+// both confirmations.
+type Trade struct {
+	ID        string        `json:"id"`
+	Accepted  bool          `json:"accepted"`
+	Offers    [2]TradeOffer `json:"offers"`
+	Locked    [2]bool       `json:"locked"`
+	Confirmed [2]bool       `json:"confirmed"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
 }
 
+// TradeRecord is a completed trade.
+type TradeRecord struct {
+	ID          string        `json:"id"`
+	Offers      [2]TradeOffer `json:"offers"`
+	CompletedAt time.Time     `json:"completed_at"`
+}
+
+// Friend is one side of a friendship.
+type Friend struct {
+	PlayerID string    `json:"player_id"`
+	Since    time.Time `json:"since"`
+}
+
+// FriendRequest is a pending request from one player to another.
+type FriendRequest struct {
+	From   string    `json:"from"`
+	SentAt time.Time `json:"sent_at"`
+}
+
+// Currencies a wallet can hold.
+const (
+	CurrencyGold    = "gold"
+	CurrencyDiamond = "diamond"
+)
+
+// LedgerEntry records one wallet change. Balance is the balance after it.
+type LedgerEntry struct {
+	ID       string    `json:"id"`
+	PlayerID string    `json:"player_id"`
+	Currency string    `json:"currency"`
+	Delta    int64     `json:"delta"`
+	Balance  int64     `json:"balance"`
+	Reason   string    `json:"reason"`
+	Source   string    `json:"source"`
+	RefID    string    `json:"ref_id"`
+	At       time.Time `json:"at"`
+}
+
+// Stats are the derived combat attributes of a player, or a bonus to them.
+type Stats struct {
+	HP      int `json:"hp"`
+	Attack  int `json:"attack"`
+	Defense int `json:"defense"`
+	Speed   int `json:"speed"`
+}
+
+// Add returns the field-wise sum of s and o.
+func (s Stats) Add(o Stats) Stats {
+	return Stats{HP: s.HP + o.HP, Attack: s.Attack + o.Attack, Defense: s.Defense + o.Defense, Speed: s.Speed + o.Speed}
+}
+
+// Buff is a temporary stat bonus on a player.
+type Buff struct {
+	ID        string    `json:"id"`
+	Source    string    `json:"source"`
+	Stats     Stats     `json:"stats"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// NameChange records one rename of a player.
+type NameChange struct {
+	PlayerID  string    `json:"player_id"`
+	OldName   string    `json:"old_name"`
+	NewName   string    `json:"new_name"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// Deleted reports whether the character is pending deletion.
+func (p Player) Deleted() bool {
+	return !p.DeletedAt.IsZero()
+}
+
+// BagEntry is one bag slot holding a stack of a single item. Slots are
+// stable indices from zero up to the bag capacity; empty slots have no entry.
 type BagEntry struct {
+	Slot     int    `json:"slot"`
 	ItemID   string `json:"item_id"`
 	Quantity int    `json:"quantity"`
 }
@@ -82,6 +409,71 @@ type Item struct {
 	Name   string `json:"name"`
 	Rarity string `json:"rarity"`
 	Price  int    `json:"price"`
+	// MaxStack is how many fit in one bag slot; zero means one.
+	MaxStack int `json:"max_stack"`
+	// Use is what happens when the item is used, which consumes it; nil
+	// means the item cannot be used.
+	Use *ItemEffect `json:"use,omitempty"`
+	// Bound items cannot be traded between players.
+	Bound bool `json:"bound,omitempty"`
+	// Slot is the equipment slot the item is worn in; empty means it
+	// cannot be equipped. Level is the player level needed to equip it.
+	Slot  string `json:"slot,omitempty"`
+	Level int    `json:"level,omitempty"`
+	// Stats is the bonus granted while the item is equipped.
+	Stats Stats `json:"stats"`
+}
+
+// Equipment slots.
+const (
+	SlotWeapon    = "weapon"
+	SlotArmor     = "armor"
+	SlotAccessory = "accessory"
+)
+
+// Item effect types.
+const (
+	EffectHeal          = "heal"
+	EffectGrantExp      = "grant_exp"
+	EffectGrantCurrency = "grant_currency"
+	EffectLootBox       = "loot_box"
+	EffectBuff          = "buff"
+)
+
+// ItemEffect binds an item to a registered effect. Which fields apply
+// depends on Type; every amount is per item used.
+type ItemEffect struct {
+	Type     string     `json:"type"`
+	Amount   int64      `json:"amount,omitempty"`
+	Currency string     `json:"currency,omitempty"`
+	Stats    Stats      `json:"stats,omitempty"`
+	Seconds  int        `json:"seconds,omitempty"`
+	Loot     []LootDrop `json:"loot,omitempty"`
+}
+
+// LootDrop is one possible roll of a loot box, picked with probability
+// Weight over the sum of all weights.
+type LootDrop struct {
+	ItemID   string `json:"item_id"`
+	Quantity int    `json:"quantity"`
+	Weight   int    `json:"weight"`
+}
+
+// Reward delivery targets for level rewards.
+const (
+	RewardToBag  = "bag"
+	RewardToMail = "mail"
+)
+
+// LevelDef is one row of the level curve. Experience is the cumulative total
+// needed to reach Level; Rewards are granted once when it is reached.
+type LevelDef struct {
+	Level      int              `json:"level"`
+	Experience int              `json:"experience"`
+	Rewards    []MailAttachment `json:"rewards,omitempty"`
+	Delivery   string           `json:"delivery,omitempty"`
+	// Stats are the base attributes of a player at this level.
+	Stats Stats `json:"stats"`
 }
 
 type ShopListing struct {
@@ -100,6 +492,8 @@ type Mail struct {
 	Subject     string           `json:"subject"`
 	Body        string           `json:"body"`
 	Attachments []MailAttachment `json:"attachments"`
+	// ClaimedAt is set once the attachments have moved into the bag.
+	ClaimedAt time.Time `json:"claimed_at"`
 }
 
 type Notice struct {
@@ -120,9 +514,11 @@ type ChatMessage struct {
 }
 
 type Room struct {
-	ID         string   `json:"id"`
-	Game       string   `json:"game"`
-	Players    []string `json:"players"`
+	ID      string   `json:"id"`
+	Game    string   `json:"game"`
+	Players []string `json:"players"`
+	// Invited lists players asked to join who have not taken a seat yet.
+	Invited    []string `json:"invited,omitempty"`
 	MaxPlayers int      `json:"max_players"`
 	Status     string   `json:"status"`
 }
@@ -0,0 +1,148 @@
+package dao
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// snapshot is the on-disk form of the tables that hold player state. Static
+// definitions (items, levels, quests, the login calendar) come from code,
+// and open trades, rooms and chat are dropped on restart.
+type snapshot struct {
+	SavedAt  time.Time          `json:"saved_at"`
+	Accounts map[string]Account `json:"accounts"`
+	// Passwords holds the hashes Account leaves out of its JSON form.
+	Passwords            map[string]string                   `json:"passwords"`
+	Credentials          map[string]string                   `json:"credentials"`
+	Players              map[string]Player                   `json:"players"`
+	NameHistory          map[string][]NameChange             `json:"name_history"`
+	Bans                 map[string][]Ban                    `json:"bans"`
+	Mails                map[string][]Mail                   `json:"mails"`
+	Bags                 map[string][]BagEntry               `json:"bags"`
+	BagRevisions         map[string]int64                    `json:"bag_revisions"`
+	Buffs                map[string][]Buff                   `json:"buffs"`
+	Ledger               []LedgerEntry                       `json:"ledger"`
+	QuestProgress        map[string]map[string]QuestProgress `json:"quest_progress"`
+	LeaderboardSnapshots []LeaderboardSnapshot               `json:"leaderboard_snapshots"`
+	Friends              map[string][]Friend                 `json:"friends"`
+	FriendRequests       map[string][]FriendRequest          `json:"friend_requests"`
+	Blocks               map[string][]string                 `json:"blocks"`
+	TradeLog             []TradeRecord                       `json:"trade_log"`
+}
+
+// Save writes every player-owned table to path as one snapshot taken under
+// the read lock, so bags, mail, equipment and wallets always agree with each
+// other. The file is replaced atomically, so a crash mid-save leaves the
+// previous snapshot intact.
+func (d *DataStore) Save(path string) error {
+	var data []byte
+	var err error
+	d.WithRead(func(store *DataStore) {
+		s := snapshot{
+			SavedAt:              time.Now(),
+			Accounts:             store.Accounts,
+			Passwords:            make(map[string]string, len(store.Accounts)),
+			Credentials:          store.Credentials,
+			Players:              store.Players,
+			NameHistory:          store.NameHistory,
+			Bans:                 store.Bans,
+			Mails:                store.Mails,
+			Bags:                 store.Bags,
+			BagRevisions:         store.BagRevisions,
+			Buffs:                store.Buffs,
+			Ledger:               store.Ledger,
+			QuestProgress:        store.QuestProgress,
+			LeaderboardSnapshots: store.LeaderboardSnapshots,
+			Friends:              store.Friends,
+			FriendRequests:       store.FriendRequests,
+			Blocks:               store.Blocks,
+			TradeLog:             store.TradeLog,
+		}
+		for id, account := range store.Accounts {
+			if account.Password != "" {
+				s.Passwords[id] = account.Password
+			}
+		}
+		data, err = json.Marshal(s)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Load replaces the player-owned tables with the snapshot at path, all
+// together, seeded demo data included. A missing file leaves the store as it
+// is.
+func (d *DataStore) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var s snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	for id, hash := range s.Passwords {
+		if account, ok := s.Accounts[id]; ok {
+			account.Password = hash
+			s.Accounts[id] = account
+		}
+	}
+
+	d.WithLock(func(store *DataStore) {
+		store.Accounts = orEmpty(s.Accounts)
+		store.Credentials = orEmpty(s.Credentials)
+		store.Players = orEmpty(s.Players)
+		store.NameHistory = orEmpty(s.NameHistory)
+		store.Bans = orEmpty(s.Bans)
+		store.Mails = orEmpty(s.Mails)
+		store.Bags = orEmpty(s.Bags)
+		store.BagRevisions = orEmpty(s.BagRevisions)
+		store.Buffs = orEmpty(s.Buffs)
+		store.Ledger = s.Ledger
+		store.QuestProgress = orEmpty(s.QuestProgress)
+		store.LeaderboardSnapshots = s.LeaderboardSnapshots
+		store.Friends = orEmpty(s.Friends)
+		store.FriendRequests = orEmpty(s.FriendRequests)
+		store.Blocks = orEmpty(s.Blocks)
+		store.TradeLog = s.TradeLog
+	})
+	return nil
+}
+
+// orEmpty returns m, or an empty map when the snapshot had none.
+func orEmpty[K comparable, V any](m map[K]V) map[K]V {
+	if m == nil {
+		return map[K]V{}
+	}
+	return m
+}
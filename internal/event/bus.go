@@ -0,0 +1,48 @@
+package event
+
+import (
+	"sync"
+	"time"
+)
+
+// Event is a gameplay fact raised by one module for others to react to.
+type Event struct {
+	Topic    string      `json:"topic"`
+	PlayerID string      `json:"player_id"`
+	Payload  interface{} `json:"payload"`
+	At       time.Time   `json:"at"`
+}
+
+// Handler reacts to a published event.
+type Handler func(Event)
+
+// Bus is an in-process publish/subscribe hub. Handlers run synchronously on
+// the publishing goroutine, so publishers must not hold store locks.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+// NewBus constructs an empty bus.
+func NewBus() *Bus {
+	return &Bus{handlers: map[string][]Handler{}}
+}
+
+// Subscribe registers handler for every event published on topic.
+func (b *Bus) Subscribe(topic string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[topic] = append(b.handlers[topic], handler)
+}
+
+// Publish delivers the event to the topic's handlers in subscription order.
+func (b *Bus) Publish(topic, playerID string, payload interface{}) {
+	b.mu.RLock()
+	handlers := append([]Handler(nil), b.handlers[topic]...)
+	b.mu.RUnlock()
+
+	e := Event{Topic: topic, PlayerID: playerID, Payload: payload, At: time.Now()}
+	for _, handler := range handlers {
+		handler(e)
+	}
+}
@@ -0,0 +1,97 @@
+package event
+
+// Topics and their payload types.
+const (
+	// LevelUp is published once per level gained; payload LevelUpPayload.
+	LevelUp = "player.level_up"
+	// PlayerLogin is published whenever a session starts acting as a
+	// character; payload PlayerLoginPayload.
+	PlayerLogin = "player.login"
+	// DailyLogin is published on a character's first login of a game day;
+	// payload DailyLoginPayload.
+	DailyLogin = "player.daily_login"
+	// PlayerLogout is published when a session stops acting as a character;
+	// no payload.
+	PlayerLogout = "player.logout"
+	// PresenceChanged is published when a player's presence state moves;
+	// payload PresencePayload.
+	PresenceChanged = "player.presence"
+	// RoomJoined is published for every player seated in a room; payload
+	// RoomPayload.
+	RoomJoined = "room.joined"
+	// RoomLeft is published when a player gives up their seat in a room;
+	// payload RoomPayload.
+	RoomLeft = "room.left"
+	// ChatSent is published for every chat message recorded; payload
+	// ChatPayload.
+	ChatSent = "chat.sent"
+	// MatchFinished is published once per seated player when a match result
+	// is reported; payload MatchPayload.
+	MatchFinished = "match.finished"
+	// QuestCompleted is published when a player reaches a quest's target;
+	// payload QuestPayload.
+	QuestCompleted = "quest.completed"
+	// EquipmentChanged is published when a player equips or unequips an
+	// item; payload EquipmentPayload.
+	EquipmentChanged = "player.equipment"
+)
+
+// LevelUpPayload describes a single level gained by a player.
+type LevelUpPayload struct {
+	Level  int    `json:"level"`
+	Reason string `json:"reason"`
+}
+
+// PlayerLoginPayload identifies the account behind a login.
+type PlayerLoginPayload struct {
+	AccountID string `json:"account_id"`
+}
+
+// PresencePayload describes a presence transition.
+type PresencePayload struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	RoomID string `json:"room_id,omitempty"`
+}
+
+// RoomPayload identifies a room.
+type RoomPayload struct {
+	RoomID string `json:"room_id"`
+	Game   string `json:"game"`
+}
+
+// ChatPayload describes a sent chat message.
+type ChatPayload struct {
+	Channel string `json:"channel"`
+	To      string `json:"to,omitempty"`
+	RoomID  string `json:"room_id,omitempty"`
+}
+
+// MatchPayload is one player's outcome of a finished match.
+type MatchPayload struct {
+	MatchID string `json:"match_id"`
+	Mode    string `json:"mode"`
+	Won     bool   `json:"won"`
+}
+
+// QuestPayload identifies a completed quest and the cycle it belongs to.
+type QuestPayload struct {
+	QuestID string `json:"quest_id"`
+	Period  string `json:"period"`
+	Cycle   string `json:"cycle,omitempty"`
+}
+
+// EquipmentPayload describes one slot change. ItemID is empty after an
+// unequip; Previous is what the slot held before.
+type EquipmentPayload struct {
+	Slot     string `json:"slot"`
+	ItemID   string `json:"item_id,omitempty"`
+	Previous string `json:"previous,omitempty"`
+}
+
+// DailyLoginPayload carries the login counters after a new game day.
+type DailyLoginPayload struct {
+	Day       string `json:"day"`
+	Streak    int    `json:"streak"`
+	TotalDays int    `json:"total_days"`
+}
@@ -0,0 +1,74 @@
+package gameday
+
+import (
+	"fmt"
+	"time"
+)
+
+const dayLayout = "2006-01-02"
+
+// Calendar maps wall-clock time to game days, which start at a configured
+// hour in a configured time zone rather than at midnight UTC.
+type Calendar struct {
+	loc       *time.Location
+	resetHour int
+}
+
+// New constructs a calendar whose days start at resetHour in loc.
+func New(loc *time.Location, resetHour int) Calendar {
+	return Calendar{loc: loc, resetHour: resetHour}
+}
+
+// Load constructs a calendar for the named IANA time zone. An unknown zone
+// falls back to UTC and is reported in the error.
+func Load(timezone string, resetHour int) (Calendar, error) {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return New(time.UTC, resetHour), fmt.Errorf("unknown timezone %q, using UTC: %w", timezone, err)
+	}
+	return New(loc, resetHour), nil
+}
+
+// Day returns the game day containing t, formatted as YYYY-MM-DD.
+func (c Calendar) Day(t time.Time) string {
+	return c.start(t).Format(dayLayout)
+}
+
+// Consecutive reports whether day directly follows previous.
+func (c Calendar) Consecutive(previous, day string) bool {
+	prev, err := time.ParseInLocation(dayLayout, previous, c.loc)
+	if err != nil {
+		return false
+	}
+	return prev.AddDate(0, 0, 1).Format(dayLayout) == day
+}
+
+// NextReset returns when the game day after t begins.
+func (c Calendar) NextReset(t time.Time) time.Time {
+	start := c.start(t)
+	return time.Date(start.Year(), start.Month(), start.Day()+1, c.resetHour, 0, 0, 0, c.loc)
+}
+
+// Week returns the game week containing t as an ISO week such as 2026-W42.
+// Weeks start on Monday at the reset hour.
+func (c Calendar) Week(t time.Time) string {
+	year, week := c.start(t).ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// NextWeeklyReset returns when the game week after t begins.
+func (c Calendar) NextWeeklyReset(t time.Time) time.Time {
+	start := c.start(t)
+	days := (8 - int(start.Weekday())) % 7
+	if days == 0 {
+		days = 7
+	}
+	return time.Date(start.Year(), start.Month(), start.Day()+days, c.resetHour, 0, 0, 0, c.loc)
+}
+
+// start returns midnight of the calendar date t falls on once shifted back
+// by the reset hour.
+func (c Calendar) start(t time.Time) time.Time {
+	local := t.In(c.loc).Add(-time.Duration(c.resetHour) * time.Hour)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, c.loc)
+}
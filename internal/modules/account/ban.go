@@ -0,0 +1,175 @@
+package account
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"goworld-skeleton/internal/auth"
+	"goworld-skeleton/internal/dao"
+)
+
+// BanError is returned by authenticate for suspended accounts.
+type BanError struct {
+	Reason    string
+	ExpiresAt time.Time
+}
+
+func (e *BanError) Error() string {
+	if e.ExpiresAt.IsZero() {
+		return "account banned permanently"
+	}
+	return "account banned until " + e.ExpiresAt.Format(time.RFC3339)
+}
+
+type banResponse struct {
+	Error     string     `json:"error"`
+	Reason    string     `json:"reason"`
+	Permanent bool       `json:"permanent"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func writeBan(w http.ResponseWriter, ban *BanError) {
+	resp := banResponse{Error: ban.Error(), Reason: ban.Reason, Permanent: ban.ExpiresAt.IsZero()}
+	if !resp.Permanent {
+		resp.ExpiresAt = &ban.ExpiresAt
+	}
+	writeJSON(w, http.StatusForbidden, resp)
+}
+
+// activeBan returns the ban in force for the account, if any. When several
+// overlap, the one that lasts longest wins. Callers must hold the store lock.
+func activeBan(store *dao.DataStore, accountID string, now time.Time) *BanError {
+	var found *BanError
+	for _, ban := range store.Bans[accountID] {
+		if !ban.ActiveAt(now) {
+			continue
+		}
+		if found == nil || ban.ExpiresAt.IsZero() || (!found.ExpiresAt.IsZero() && ban.ExpiresAt.After(found.ExpiresAt)) {
+			found = &BanError{Reason: ban.Reason, ExpiresAt: ban.ExpiresAt}
+		}
+		if found.ExpiresAt.IsZero() {
+			break
+		}
+	}
+	return found
+}
+
+func (s Service) checkBan(accountID string) error {
+	var ban *BanError
+	s.store.WithRead(func(store *dao.DataStore) {
+		ban = activeBan(store, accountID, time.Now())
+	})
+	if ban != nil {
+		return ban
+	}
+	return nil
+}
+
+type banInput struct {
+	AccountID string    `json:"account_id"`
+	Reason    string    `json:"reason"`
+	Until     time.Time `json:"until"`
+}
+
+// ban suspends an account until the given time, or permanently when no
+// time is given, and signs it out everywhere.
+func (s Service) ban(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	var input banInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if input.AccountID == "" || input.Reason == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "account_id and reason required"})
+		return
+	}
+
+	now := time.Now()
+	if !input.Until.IsZero() && !input.Until.After(now) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "until must be in the future"})
+		return
+	}
+
+	ban := dao.Ban{
+		AccountID: input.AccountID,
+		Reason:    input.Reason,
+		Operator:  auth.Operator(r),
+		CreatedAt: now,
+		ExpiresAt: input.Until,
+	}
+	var found bool
+	s.store.WithLock(func(store *dao.DataStore) {
+		if _, found = store.Accounts[input.AccountID]; !found {
+			return
+		}
+		history := store.Bans[input.AccountID]
+		ban.ID = input.AccountID + "-" + strconv.Itoa(len(history)+1)
+		store.Bans[input.AccountID] = append(history, ban)
+	})
+
+	if !found {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "account not found"})
+		return
+	}
+
+	revoked := s.sessions.RevokeAll(input.AccountID)
+	s.logger.Printf("operator %s banned %s (%s), revoked %d sessions", ban.Operator, ban.AccountID, ban.Reason, revoked)
+	writeJSON(w, http.StatusCreated, ban)
+}
+
+type unbanInput struct {
+	AccountID string `json:"account_id"`
+}
+
+// unban lifts every active ban on the account. History is kept.
+func (s Service) unban(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	var input unbanInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	operator := auth.Operator(r)
+	now := time.Now()
+	lifted := 0
+	s.store.WithLock(func(store *dao.DataStore) {
+		history := store.Bans[input.AccountID]
+		for i := range history {
+			if history[i].ActiveAt(now) {
+				history[i].LiftedAt = now
+				history[i].LiftedBy = operator
+				lifted++
+			}
+		}
+	})
+
+	s.logger.Printf("operator %s lifted %d bans on %s", operator, lifted, input.AccountID)
+	writeJSON(w, http.StatusOK, map[string]int{"lifted": lifted})
+}
+
+// banHistory lists all bans for ?account_id=, including expired and lifted ones.
+func (s Service) banHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+
+	accountID := r.URL.Query().Get("account_id")
+	history := make([]dao.Ban, 0)
+	s.store.WithRead(func(store *dao.DataStore) {
+		history = append(history, store.Bans[accountID]...)
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{"account_id": accountID, "bans": history})
+}
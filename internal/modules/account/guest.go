@@ -0,0 +1,227 @@
+package account
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"goworld-skeleton/internal/auth"
+	"goworld-skeleton/internal/dao"
+	"goworld-skeleton/internal/modules/player"
+)
+
+const minDeviceIDLength = 8
+
+var (
+	errCredentialTaken = errors.New("credential already belongs to another account")
+	errAlreadyBound    = errors.New("account already has a username")
+)
+
+func usernameCredential(username string) string { return "username:" + username }
+func deviceCredential(deviceID string) string   { return "device:" + deviceID }
+
+type guestInput struct {
+	DeviceID string `json:"device_id"`
+	// Password is required once the account has bound a username, so the
+	// device ID alone no longer signs in.
+	Password string `json:"password"`
+	Device   string `json:"device"`
+}
+
+// guestLogin signs in with a device ID, creating a guest account and player
+// the first time the device is seen. Attempts share the login throttle's
+// per-IP lockout, and each IP may only create a few guests per window.
+func (s Service) guestLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	var input guestInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if len(input.DeviceID) < minDeviceIDLength {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "device_id too short"})
+		return
+	}
+
+	ip := auth.ClientIP(r)
+	var lockout *LockoutError
+	if errors.As(s.throttle.checkIP(ip), &lockout) {
+		writeLockout(w, lockout)
+		return
+	}
+
+	guestID, err := newGuestID()
+	var character dao.Player
+	if err == nil {
+		character, err = player.NewCharacter(guestID, "")
+	}
+	if err != nil {
+		s.logger.Printf("create guest: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "could not create guest"})
+		return
+	}
+
+	var account dao.Account
+	var created bool
+	s.store.WithLock(func(store *dao.DataStore) {
+		credential := deviceCredential(input.DeviceID)
+		if id, ok := store.Credentials[credential]; ok {
+			account = store.Accounts[id]
+			return
+		}
+		if errors.As(s.throttle.checkGuests(ip), &lockout) {
+			return
+		}
+		if _, exists := store.Accounts[guestID]; exists {
+			return
+		}
+		account = dao.Account{ID: guestID, Guest: true}
+		store.Accounts[account.ID] = account
+		store.Credentials[credential] = account.ID
+		character.Name = player.AvailableName(store, s.names, "Guest-"+guestID[len(guestID)-6:], "Guest-"+guestID[len(guestID)-10:])
+		store.Players[character.ID] = character
+		created = true
+	})
+
+	if lockout != nil {
+		writeLockout(w, lockout)
+		return
+	}
+	if account.ID == "" {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "could not create guest, retry"})
+		return
+	}
+	if created {
+		s.throttle.createdGuest(ip)
+	}
+	if account.Password != "" {
+		if input.Password == "" {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "this account has a password, send it with the device_id"})
+			return
+		}
+		if !s.confirmPassword(w, r, account, input.Password) {
+			return
+		}
+	}
+
+	var banned *BanError
+	if errors.As(s.checkBan(account.ID), &banned) {
+		writeBan(w, banned)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+		s.logger.Printf("created guest account %s", account.ID)
+	}
+	s.issueSession(w, r, status, account, input.Device)
+}
+
+type bindInput struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	DeviceID string `json:"device_id"`
+}
+
+// bind attaches a username/password or another device to the logged-in
+// account. The account ID, and with it the player's progress, is unchanged.
+// Once a password is bound, device logins must include it.
+func (s Service) bind(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	var input bindInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	accountID := auth.AccountID(r)
+	var err error
+	switch {
+	case input.Username != "" || input.Password != "":
+		err = s.bindUsername(accountID, input.Username, input.Password)
+	case input.DeviceID != "":
+		err = s.bindDevice(accountID, input.DeviceID)
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "username and password or device_id required"})
+		return
+	}
+
+	switch {
+	case errors.Is(err, errCredentialTaken), errors.Is(err, errAlreadyBound):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+	case err != nil:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	default:
+		s.logger.Printf("account %s bound a new credential", accountID)
+		writeJSON(w, http.StatusOK, map[string]string{"status": "bound"})
+	}
+}
+
+func (s Service) bindUsername(accountID, username, password string) error {
+	if username == "" || password == "" {
+		return errors.New("username and password required")
+	}
+
+	hashed, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	s.store.WithLock(func(store *dao.DataStore) {
+		account, ok := store.Accounts[accountID]
+		switch {
+		case !ok:
+			err = errors.New("account not found")
+		case account.Username != "":
+			err = errAlreadyBound
+		default:
+			credential := usernameCredential(username)
+			if _, taken := store.Credentials[credential]; taken {
+				err = errCredentialTaken
+				return
+			}
+			account.Username = username
+			account.Password = hashed
+			account.Guest = false
+			store.Accounts[accountID] = account
+			store.Credentials[credential] = accountID
+		}
+	})
+	return err
+}
+
+func (s Service) bindDevice(accountID, deviceID string) error {
+	if len(deviceID) < minDeviceIDLength {
+		return errors.New("device_id too short")
+	}
+
+	var err error
+	s.store.WithLock(func(store *dao.DataStore) {
+		credential := deviceCredential(deviceID)
+		if owner, taken := store.Credentials[credential]; taken {
+			if owner != accountID {
+				err = errCredentialTaken
+			}
+			return
+		}
+		store.Credentials[credential] = accountID
+	})
+	return err
+}
+
+func newGuestID() (string, error) {
+	id, err := randomHex(8)
+	if err != nil {
+		return "", err
+	}
+	return "guest-" + id, nil
+}
@@ -0,0 +1,150 @@
+package account
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Password hashes are stored as
+//
+//	$pbkdf2-sha256$v=<version>$i=<iterations>$<salt>$<hash>
+//
+// so the work factor can be raised later without invalidating existing
+// records. Anything without the prefix is treated as a legacy plaintext
+// password and re-hashed on the next successful login.
+const (
+	hashScheme       = "pbkdf2-sha256"
+	hashVersion      = 1
+	hashIterations   = 600000
+	hashSaltLength   = 16
+	hashKeyLength    = 32
+	hashPrefix       = "$" + hashScheme + "$"
+	hashSegmentCount = 6
+)
+
+type passwordHash struct {
+	version    int
+	iterations int
+	salt       []byte
+	key        []byte
+}
+
+// hashPassword derives a salted hash using the current parameters.
+func hashPassword(password string) (string, error) {
+	salt := make([]byte, hashSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("generate salt: %w", err)
+	}
+	h := passwordHash{
+		version:    hashVersion,
+		iterations: hashIterations,
+		salt:       salt,
+		key:        pbkdf2SHA256([]byte(password), salt, hashIterations, hashKeyLength),
+	}
+	return h.encode(), nil
+}
+
+// dummyHash is verified against when a login names no account, so unknown
+// usernames take as long to reject as wrong passwords.
+var dummyHash = sync.OnceValue(func() string {
+	if hashed, err := hashPassword(""); err == nil {
+		return hashed
+	}
+	h := passwordHash{version: hashVersion, iterations: hashIterations, salt: make([]byte, hashSaltLength), key: make([]byte, hashKeyLength)}
+	return h.encode()
+})
+
+// verifyPassword reports whether password matches the stored value and
+// whether the stored value should be replaced with a fresh hash.
+func verifyPassword(stored, password string) (ok bool, rehash bool) {
+	if !strings.HasPrefix(stored, hashPrefix) {
+		// Do a hash's worth of work anyway so legacy accounts cannot be
+		// told apart by timing.
+		verifyPassword(dummyHash(), password)
+		ok = subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1
+		return ok, ok
+	}
+
+	h, err := decodePasswordHash(stored)
+	if err != nil {
+		return false, false
+	}
+
+	key := pbkdf2SHA256([]byte(password), h.salt, h.iterations, len(h.key))
+	if subtle.ConstantTimeCompare(key, h.key) != 1 {
+		return false, false
+	}
+	return true, h.version < hashVersion || h.iterations < hashIterations
+}
+
+func (h passwordHash) encode() string {
+	enc := base64.RawStdEncoding
+	return fmt.Sprintf("%sv=%d$i=%d$%s$%s", hashPrefix, h.version, h.iterations, enc.EncodeToString(h.salt), enc.EncodeToString(h.key))
+}
+
+func decodePasswordHash(stored string) (passwordHash, error) {
+	parts := strings.Split(stored, "$")
+	if len(parts) != hashSegmentCount || parts[1] != hashScheme {
+		return passwordHash{}, fmt.Errorf("unsupported password hash")
+	}
+
+	version, err := strconv.Atoi(strings.TrimPrefix(parts[2], "v="))
+	if err != nil {
+		return passwordHash{}, fmt.Errorf("invalid hash version: %w", err)
+	}
+	iterations, err := strconv.Atoi(strings.TrimPrefix(parts[3], "i="))
+	if err != nil || iterations < 1 {
+		return passwordHash{}, fmt.Errorf("invalid hash iterations")
+	}
+
+	enc := base64.RawStdEncoding
+	salt, err := enc.DecodeString(parts[4])
+	if err != nil {
+		return passwordHash{}, fmt.Errorf("invalid hash salt: %w", err)
+	}
+	key, err := enc.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return passwordHash{}, fmt.Errorf("invalid hash key")
+	}
+
+	return passwordHash{version: version, iterations: iterations, salt: salt, key: key}, nil
+}
+
+// pbkdf2SHA256 implements PBKDF2 (RFC 8018) with HMAC-SHA256.
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hashLen := prf.Size()
+	blocks := (keyLen + hashLen - 1) / hashLen
+
+	out := make([]byte, 0, blocks*hashLen)
+	var counter [4]byte
+	u := make([]byte, hashLen)
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(counter[:], uint32(block))
+		prf.Write(counter[:])
+		u = prf.Sum(u[:0])
+
+		t := make([]byte, hashLen)
+		copy(t, u)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		out = append(out, t...)
+	}
+	return out[:keyLen]
+}
@@ -0,0 +1,98 @@
+package account
+
+import (
+	"encoding/hex"
+	"io"
+	"log"
+	"strings"
+	"testing"
+
+	"goworld-skeleton/internal/dao"
+)
+
+func TestPBKDF2SHA256(t *testing.T) {
+	// Vectors in the style of RFC 6070, for HMAC-SHA256, and from RFC 7914
+	// section 11.
+	tests := []struct {
+		password, salt string
+		iterations     int
+		want           string
+	}{
+		{"password", "salt", 1, "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{"password", "salt", 2, "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+		{"password", "salt", 4096, "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"},
+		{"passwordPASSWORDpassword", "saltSALTsaltSALTsaltSALTsaltSALTsalt", 4096, "348c89dbcbd32b2f32d814b8116e84cf2b17347ebc1800181c4e2a1fb8dd53e1c635518c7dac47e9"},
+		{"pass\x00word", "sa\x00lt", 4096, "89b69d0516f829893c696226650a8687"},
+		{"passwd", "salt", 1, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"},
+		{"Password", "NaCl", 80000, "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d"},
+	}
+	for _, tt := range tests {
+		want, _ := hex.DecodeString(tt.want)
+		got := pbkdf2SHA256([]byte(tt.password), []byte(tt.salt), tt.iterations, len(want))
+		if hex.EncodeToString(got) != tt.want {
+			t.Errorf("pbkdf2SHA256(%q, %q, %d) = %x, want %s", tt.password, tt.salt, tt.iterations, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyPassword(t *testing.T) {
+	hashed, err := hashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hashed, hashPrefix) {
+		t.Fatalf("hash %q lacks prefix %q", hashed, hashPrefix)
+	}
+	weak := passwordHash{version: hashVersion, iterations: 1000, salt: []byte("0123456789abcdef")}
+	weak.key = pbkdf2SHA256([]byte("correct horse"), weak.salt, weak.iterations, hashKeyLength)
+
+	tests := []struct {
+		name             string
+		stored, password string
+		ok, rehash       bool
+	}{
+		{name: "current hash", stored: hashed, password: "correct horse", ok: true},
+		{name: "wrong password", stored: hashed, password: "battery staple"},
+		{name: "outdated iterations", stored: weak.encode(), password: "correct horse", ok: true, rehash: true},
+		{name: "plaintext", stored: "hunter2", password: "hunter2", ok: true, rehash: true},
+		{name: "plaintext mismatch", stored: "hunter2", password: "hunter3"},
+		{name: "corrupt hash", stored: hashPrefix + "v=1$i=x$$", password: "correct horse"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, rehash := verifyPassword(tt.stored, tt.password)
+			if ok != tt.ok || rehash != tt.rehash {
+				t.Errorf("verifyPassword = %v, %v; want %v, %v", ok, rehash, tt.ok, tt.rehash)
+			}
+		})
+	}
+}
+
+func TestAuthenticateUpgradesPlaintext(t *testing.T) {
+	store := dao.NewDataStore()
+	s := Service{store: store, logger: log.New(io.Discard, "", 0)}
+	store.Accounts["legacy"] = dao.Account{ID: "legacy", Username: "legacy", Password: "hunter2"}
+	store.Credentials[usernameCredential("legacy")] = "legacy"
+
+	if _, err := s.authenticate("legacy", "wrong"); err == nil {
+		t.Fatal("authenticate accepted a wrong password")
+	}
+	if got := store.Accounts["legacy"].Password; got != "hunter2" {
+		t.Fatalf("failed login changed the password to %q", got)
+	}
+
+	if _, err := s.authenticate("legacy", "hunter2"); err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	stored := store.Accounts["legacy"].Password
+	if !strings.HasPrefix(stored, hashPrefix) {
+		t.Fatalf("password was not upgraded, still %q", stored)
+	}
+	if ok, rehash := verifyPassword(stored, "hunter2"); !ok || rehash {
+		t.Errorf("upgraded hash verifies as %v, rehash %v", ok, rehash)
+	}
+
+	if _, err := s.authenticate("nobody", "hunter2"); err == nil {
+		t.Error("authenticate accepted an unknown username")
+	}
+}
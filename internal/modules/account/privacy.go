@@ -0,0 +1,348 @@
+package account
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"goworld-skeleton/internal/auth"
+	"goworld-skeleton/internal/dao"
+	"goworld-skeleton/internal/modules/friend"
+	"goworld-skeleton/internal/modules/leaderboard"
+	"goworld-skeleton/internal/modules/player"
+	"goworld-skeleton/internal/modules/trade"
+	"goworld-skeleton/internal/presence"
+)
+
+// deletedPlayerID replaces the author of chat messages sent by erased
+// characters.
+const deletedPlayerID = "deleted-player"
+
+// snapshotRank is one of the account's rows in a leaderboard snapshot.
+type snapshotRank struct {
+	Board  string `json:"board"`
+	Mode   string `json:"mode"`
+	Period string `json:"period"`
+	dao.RankEntry
+}
+
+// accountExport is everything the server holds about an account.
+type accountExport struct {
+	ExportedAt  time.Time                         `json:"exported_at"`
+	Account     dao.Account                       `json:"account"`
+	Credentials []string                          `json:"credentials"`
+	Players     []dao.Player                      `json:"players"`
+	Bags        map[string][]dao.BagEntry         `json:"bags"`
+	Mails       map[string][]dao.Mail             `json:"mails"`
+	Friends     map[string][]dao.Friend           `json:"friends"`
+	Blocks      map[string][]string               `json:"blocks"`
+	Quests      map[string][]dao.QuestProgress    `json:"quests"`
+	Trades      []dao.TradeRecord                 `json:"trades"`
+	Rankings    []snapshotRank                    `json:"rankings"`
+	Standings   map[string][]leaderboard.Standing `json:"standings"`
+	Presence    []presence.Status                 `json:"presence"`
+	ChatsSent   []dao.ChatMessage                 `json:"chats_sent"`
+	Rooms       []dao.Room                        `json:"rooms"`
+	NameHistory []dao.NameChange                  `json:"name_history"`
+	Ledger      []dao.LedgerEntry                 `json:"ledger"`
+	Bans        []dao.Ban                         `json:"bans"`
+	Sessions    []auth.Session                    `json:"sessions"`
+}
+
+func (s Service) export(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+
+	accountID := auth.AccountID(r)
+	doc := accountExport{
+		ExportedAt:  time.Now(),
+		Credentials: make([]string, 0),
+		Bags:        map[string][]dao.BagEntry{},
+		Mails:       map[string][]dao.Mail{},
+		Friends:     map[string][]dao.Friend{},
+		Blocks:      map[string][]string{},
+		Quests:      map[string][]dao.QuestProgress{},
+		Trades:      make([]dao.TradeRecord, 0),
+		Rankings:    make([]snapshotRank, 0),
+		Standings:   map[string][]leaderboard.Standing{},
+		Presence:    make([]presence.Status, 0),
+		ChatsSent:   make([]dao.ChatMessage, 0),
+		NameHistory: make([]dao.NameChange, 0),
+		Ledger:      make([]dao.LedgerEntry, 0),
+		Rooms:       make([]dao.Room, 0),
+		Sessions:    s.sessions.List(accountID),
+	}
+
+	s.store.WithRead(func(store *dao.DataStore) {
+		doc.Account = store.Accounts[accountID]
+		for credential, owner := range store.Credentials {
+			if owner == accountID {
+				doc.Credentials = append(doc.Credentials, credential)
+			}
+		}
+		sort.Strings(doc.Credentials)
+
+		doc.Players = player.Characters(store, accountID)
+		owned := map[string]bool{}
+		for _, p := range doc.Players {
+			owned[p.ID] = true
+			if bag, ok := store.Bags[p.ID]; ok {
+				doc.Bags[p.ID] = append([]dao.BagEntry(nil), bag...)
+			}
+			if mails, ok := store.Mails[p.ID]; ok {
+				doc.Mails[p.ID] = append([]dao.Mail(nil), mails...)
+			}
+			if friends, ok := store.Friends[p.ID]; ok {
+				doc.Friends[p.ID] = append([]dao.Friend(nil), friends...)
+			}
+			if blocked, ok := store.Blocks[p.ID]; ok {
+				doc.Blocks[p.ID] = append([]string(nil), blocked...)
+			}
+			for _, progress := range store.QuestProgress[p.ID] {
+				doc.Quests[p.ID] = append(doc.Quests[p.ID], progress)
+			}
+			doc.NameHistory = append(doc.NameHistory, store.NameHistory[p.ID]...)
+		}
+
+		for _, msg := range store.Chats {
+			if owned[msg.From] {
+				doc.ChatsSent = append(doc.ChatsSent, msg)
+			}
+		}
+		for _, entry := range store.Ledger {
+			if owned[entry.PlayerID] {
+				doc.Ledger = append(doc.Ledger, entry)
+			}
+		}
+		for _, record := range store.TradeLog {
+			if owned[record.Offers[0].PlayerID] || owned[record.Offers[1].PlayerID] {
+				doc.Trades = append(doc.Trades, record)
+			}
+		}
+		for _, snapshot := range store.LeaderboardSnapshots {
+			for _, entry := range snapshot.Entries {
+				if owned[entry.PlayerID] {
+					doc.Rankings = append(doc.Rankings, snapshotRank{Board: snapshot.Board, Mode: snapshot.Mode, Period: snapshot.Period, RankEntry: entry})
+				}
+			}
+		}
+		for _, room := range store.Rooms {
+			for _, seat := range room.Players {
+				if owned[seat] {
+					doc.Rooms = append(doc.Rooms, room)
+					break
+				}
+			}
+		}
+		doc.Bans = append([]dao.Ban{}, store.Bans[accountID]...)
+	})
+
+	if doc.Account.ID == "" {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "account not found"})
+		return
+	}
+	for _, p := range doc.Players {
+		doc.Standings[p.ID] = s.boards.Standings(p.ID)
+		doc.Presence = append(doc.Presence, s.tracker.Get(p.ID))
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="account-export.json"`)
+	writeJSON(w, http.StatusOK, doc)
+}
+
+type deletionInput struct {
+	Password string `json:"password"`
+}
+
+// requestDeletion schedules the caller's account for erasure after the
+// cooldown. Accounts with a password must confirm it.
+func (s Service) requestDeletion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	var input deletionInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	accountID := auth.AccountID(r)
+	var account dao.Account
+	s.store.WithRead(func(store *dao.DataStore) { account = store.Accounts[accountID] })
+	if account.Password != "" && !s.confirmPassword(w, r, account, input.Password) {
+		return
+	}
+
+	scheduled := time.Now().Add(s.cooldown)
+	s.store.WithLock(func(store *dao.DataStore) {
+		account, ok := store.Accounts[accountID]
+		if !ok {
+			return
+		}
+		if !account.DeletionScheduledAt.IsZero() {
+			scheduled = account.DeletionScheduledAt
+			return
+		}
+		account.DeletionScheduledAt = scheduled
+		store.Accounts[accountID] = account
+	})
+
+	s.logger.Printf("account %s scheduled for deletion at %s", accountID, scheduled.Format(time.RFC3339))
+	writeJSON(w, http.StatusAccepted, map[string]time.Time{"deletion_scheduled_at": scheduled})
+}
+
+func (s Service) cancelDeletion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	accountID := auth.AccountID(r)
+	var pending bool
+	s.store.WithLock(func(store *dao.DataStore) {
+		account, ok := store.Accounts[accountID]
+		if !ok || account.DeletionScheduledAt.IsZero() {
+			return
+		}
+		pending = true
+		account.DeletionScheduledAt = time.Time{}
+		store.Accounts[accountID] = account
+	})
+
+	if !pending {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "no deletion pending"})
+		return
+	}
+
+	s.logger.Printf("account %s cancelled deletion", accountID)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deletion cancelled"})
+}
+
+// SweepDeletions erases accounts whose deletion cooldown has passed, checking
+// every interval. It blocks, so run it in its own goroutine.
+func (s Service) SweepDeletions(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		s.eraseDueAccounts(now)
+	}
+}
+
+func (s Service) eraseDueAccounts(now time.Time) {
+	var erased, characters []string
+	s.store.WithLock(func(store *dao.DataStore) {
+		for id, account := range store.Accounts {
+			if !account.DeletionScheduledAt.IsZero() && !now.Before(account.DeletionScheduledAt) {
+				characters = append(characters, eraseAccount(store, id)...)
+				erased = append(erased, id)
+			}
+		}
+	})
+
+	for _, id := range characters {
+		s.tracker.Forget(id)
+		s.boards.Remove(id)
+	}
+	for _, id := range erased {
+		s.sessions.RevokeAll(id)
+		s.logger.Printf("account %s erased", id)
+	}
+}
+
+// eraseAccount removes the account's personal data from every table and
+// anonymizes its chat, ledger, trade and leaderboard history. It returns the
+// erased character IDs, whose presence and live leaderboard entries in the
+// cache are left to the caller. Callers must hold the store write lock.
+func eraseAccount(store *dao.DataStore, accountID string) []string {
+	owned := map[string]bool{}
+	var characters []string
+	for _, p := range player.Characters(store, accountID) {
+		owned[p.ID] = true
+		characters = append(characters, p.ID)
+		delete(store.Players, p.ID)
+		delete(store.Bags, p.ID)
+		delete(store.BagRevisions, p.ID)
+		delete(store.Mails, p.ID)
+		delete(store.NameHistory, p.ID)
+		delete(store.Buffs, p.ID)
+		delete(store.QuestProgress, p.ID)
+		friend.Forget(store, p.ID)
+		trade.Abandon(store, p.ID)
+	}
+
+	for i, msg := range store.Chats {
+		if owned[msg.From] {
+			store.Chats[i].From = deletedPlayerID
+		}
+		if owned[msg.To] {
+			store.Chats[i].To = deletedPlayerID
+		}
+	}
+
+	// The ledger and trade log are kept for economy audits but no longer
+	// point at the player.
+	for i, entry := range store.Ledger {
+		if owned[entry.PlayerID] {
+			store.Ledger[i].PlayerID = deletedPlayerID
+		}
+	}
+	for i, record := range store.TradeLog {
+		for j, offer := range record.Offers {
+			if owned[offer.PlayerID] {
+				store.TradeLog[i].Offers[j].PlayerID = deletedPlayerID
+			}
+		}
+	}
+
+	// Leaderboard snapshots keep the erased player's rank so the standings
+	// stay intact, but not their ID or name. Entries are copied because
+	// readers may still hold the old slice.
+	for i, snapshot := range store.LeaderboardSnapshots {
+		var entries []dao.RankEntry
+		for j, entry := range snapshot.Entries {
+			if !owned[entry.PlayerID] {
+				continue
+			}
+			if entries == nil {
+				entries = append([]dao.RankEntry(nil), snapshot.Entries...)
+			}
+			entries[j].PlayerID = deletedPlayerID
+			entries[j].Name = deletedPlayerID
+		}
+		if entries != nil {
+			store.LeaderboardSnapshots[i].Entries = entries
+		}
+	}
+
+	for id, room := range store.Rooms {
+		seats := room.Players[:0:0]
+		for _, seat := range room.Players {
+			if !owned[seat] {
+				seats = append(seats, seat)
+			}
+		}
+		room.Players = seats
+		invited := room.Invited[:0:0]
+		for _, invitee := range room.Invited {
+			if !owned[invitee] {
+				invited = append(invited, invitee)
+			}
+		}
+		room.Invited = invited
+		store.Rooms[id] = room
+	}
+
+	for credential, owner := range store.Credentials {
+		if owner == accountID {
+			delete(store.Credentials, credential)
+		}
+	}
+	delete(store.Bans, accountID)
+	delete(store.Accounts, accountID)
+	return characters
+}
@@ -0,0 +1,282 @@
+package account
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"goworld-skeleton/internal/auth"
+	"goworld-skeleton/internal/dao"
+	"goworld-skeleton/internal/modules/player"
+)
+
+const (
+	resetCodeDigits      = 6
+	resetCodeTTL         = 10 * time.Minute
+	resetCodeMaxAttempts = 5
+	resetCodeCooldown    = time.Minute
+	resetCodeKeyPrefix   = "password:reset:"
+	resetTriesKeyPrefix  = "password:reset:tries:"
+	resetSentKeyPrefix   = "password:reset:sent:"
+)
+
+var errInvalidResetCode = errors.New("invalid or expired code")
+
+// CodeSender delivers one-time password reset codes to a player.
+type CodeSender interface {
+	SendResetCode(accountID, code string, ttl time.Duration) error
+}
+
+// MailboxSender delivers codes as in-game mail, so resets work without any
+// outside services.
+type MailboxSender struct {
+	Store *dao.DataStore
+}
+
+// SendResetCode puts the code into the mailbox of every live character on
+// the account.
+func (m MailboxSender) SendResetCode(accountID, code string, ttl time.Duration) error {
+	id, err := randomHex(8)
+	if err != nil {
+		return err
+	}
+	mail := dao.Mail{
+		ID:      "reset-" + id,
+		Subject: "密码重置验证码",
+		Body:    fmt.Sprintf("你的验证码是 %s，%d 分钟内有效。如非本人操作请忽略。", code, int(ttl/time.Minute)),
+	}
+
+	delivered := 0
+	m.Store.WithLock(func(store *dao.DataStore) {
+		for _, character := range player.Characters(store, accountID) {
+			if !character.Deleted() {
+				store.Mails[character.ID] = append(store.Mails[character.ID], mail)
+				delivered++
+			}
+		}
+	})
+	if delivered == 0 {
+		return errors.New("account has no character to receive mail")
+	}
+	return nil
+}
+
+// WithCodeSender returns a copy of the service that delivers reset codes
+// through sender.
+func (s Service) WithCodeSender(sender CodeSender) Service {
+	s.codes = sender
+	return s
+}
+
+type changePasswordInput struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+	Device      string `json:"device"`
+}
+
+// changePassword replaces the password of the logged-in account, signs out
+// every session and returns a fresh token for the caller.
+func (s Service) changePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	var input changePasswordInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if input.NewPassword == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "new_password required"})
+		return
+	}
+
+	accountID := auth.AccountID(r)
+	var account dao.Account
+	s.store.WithRead(func(store *dao.DataStore) { account = store.Accounts[accountID] })
+	if account.Password == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "account has no password, bind one first"})
+		return
+	}
+	if !s.confirmPassword(w, r, account, input.OldPassword) {
+		return
+	}
+
+	if err := s.setPassword(accountID, input.NewPassword); err != nil {
+		s.logger.Printf("change password for %s: %v", accountID, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "could not change password"})
+		return
+	}
+
+	s.logger.Printf("user %s changed password", accountID)
+	s.issueSession(w, r, http.StatusOK, account, input.Device)
+}
+
+type resetRequestInput struct {
+	Username string `json:"username"`
+}
+
+// requestReset sends a one-time code to the account. It answers the same
+// way whether or not the username exists.
+func (s Service) requestReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	var input resetRequestInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var accountID string
+	s.store.WithRead(func(store *dao.DataStore) {
+		accountID = store.Credentials[usernameCredential(input.Username)]
+	})
+
+	if accountID != "" {
+		if err := s.sendResetCode(accountID); err != nil {
+			s.logger.Printf("send reset code to %s: %v", accountID, err)
+		}
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "if the account exists, a code has been sent"})
+}
+
+// sendResetCode issues a new code at most once per resetCodeCooldown, so
+// re-requesting cannot be used to reset the guess counter quickly.
+func (s Service) sendResetCode(accountID string) error {
+	if _, recent := s.cache.Get(resetSentKeyPrefix + accountID); recent {
+		return nil
+	}
+	s.cache.Set(resetSentKeyPrefix+accountID, true, resetCodeCooldown)
+
+	code, err := randomDigits(resetCodeDigits)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256([]byte(code))
+	s.cache.Set(resetCodeKeyPrefix+accountID, digest, resetCodeTTL)
+	s.cache.Delete(resetTriesKeyPrefix + accountID)
+	return s.codes.SendResetCode(accountID, code, resetCodeTTL)
+}
+
+type resetConfirmInput struct {
+	Username    string `json:"username"`
+	Code        string `json:"code"`
+	NewPassword string `json:"new_password"`
+}
+
+// confirmReset sets a new password using a code from requestReset and signs
+// out every session of the account.
+func (s Service) confirmReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	var input resetConfirmInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if input.NewPassword == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "new_password required"})
+		return
+	}
+
+	var accountID string
+	s.store.WithRead(func(store *dao.DataStore) {
+		accountID = store.Credentials[usernameCredential(input.Username)]
+	})
+	if accountID == "" || !s.consumeResetCode(accountID, input.Code) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": errInvalidResetCode.Error()})
+		return
+	}
+
+	if err := s.setPassword(accountID, input.NewPassword); err != nil {
+		s.logger.Printf("reset password for %s: %v", accountID, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "could not reset password"})
+		return
+	}
+
+	s.logger.Printf("user %s reset password", accountID)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "password reset"})
+}
+
+// consumeResetCode checks a code and burns it on success. A code is also
+// burned after too many wrong guesses.
+func (s Service) consumeResetCode(accountID, code string) bool {
+	value, ok := s.cache.Get(resetCodeKeyPrefix + accountID)
+	if !ok {
+		return false
+	}
+	expected, ok := value.([sha256.Size]byte)
+	if !ok {
+		return false
+	}
+
+	digest := sha256.Sum256([]byte(code))
+	if subtle.ConstantTimeCompare(digest[:], expected[:]) == 1 {
+		s.cache.Delete(resetCodeKeyPrefix + accountID)
+		s.cache.Delete(resetTriesKeyPrefix + accountID)
+		return true
+	}
+
+	if s.cache.Incr(resetTriesKeyPrefix+accountID, resetCodeTTL) >= resetCodeMaxAttempts {
+		s.cache.Delete(resetCodeKeyPrefix + accountID)
+	}
+	return false
+}
+
+// setPassword stores a new password hash and revokes all sessions.
+func (s Service) setPassword(accountID, password string) error {
+	hashed, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	s.store.WithLock(func(store *dao.DataStore) {
+		account, ok := store.Accounts[accountID]
+		if !ok {
+			err = errors.New("account not found")
+			return
+		}
+		account.Password = hashed
+		store.Accounts[accountID] = account
+	})
+	if err != nil {
+		return err
+	}
+
+	s.sessions.RevokeAll(accountID)
+	return nil
+}
+
+func randomDigits(n int) (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < n; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+	v, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", n, v), nil
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
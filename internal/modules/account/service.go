@@ -7,31 +7,75 @@ import (
 	"net/http"
 	"time"
 
+	"goworld-skeleton/internal/auth"
+	"goworld-skeleton/internal/config"
 	"goworld-skeleton/internal/dao"
+	"goworld-skeleton/internal/event"
+	"goworld-skeleton/internal/modules/leaderboard"
+	"goworld-skeleton/internal/modules/player"
+	"goworld-skeleton/internal/presence"
 	cache "goworld-skeleton/internal/redis"
 )
 
 // Service exposes account use cases.
 type Service struct {
-	store  *dao.DataStore
-	cache  *cache.Cache
-	logger *log.Logger
+	store    *dao.DataStore
+	cache    *cache.Cache
+	sessions *auth.Manager
+	tracker  *presence.Tracker
+	boards   leaderboard.Service
+	throttle loginThrottle
+	codes    CodeSender
+	names    config.Names
+	cooldown time.Duration
+	events   *event.Bus
+	logger   *log.Logger
 }
 
-// NewService constructs an account service.
-func NewService(store *dao.DataStore, cache *cache.Cache, logger *log.Logger) Service {
-	return Service{store: store, cache: cache, logger: logger}
+// NewService constructs an account service. The presence tracker and
+// leaderboards are only used to export and erase a player's records there.
+func NewService(store *dao.DataStore, cache *cache.Cache, sessions *auth.Manager, tracker *presence.Tracker, boards leaderboard.Service, cfg config.Config, events *event.Bus, logger *log.Logger) Service {
+	return Service{
+		store:    store,
+		cache:    cache,
+		sessions: sessions,
+		tracker:  tracker,
+		boards:   boards,
+		throttle: loginThrottle{cache: cache, cfg: cfg.LoginThrottle},
+		codes:    MailboxSender{Store: store},
+		names:    cfg.Names,
+		cooldown: cfg.DeletionCooldown,
+		events:   events,
+		logger:   logger,
+	}
 }
 
 // Register registers HTTP handlers on the provided mux.
 func (s Service) Register(mux *http.ServeMux) {
 	mux.HandleFunc("/api/account/register", s.register)
 	mux.HandleFunc("/api/account/login", s.login)
+	mux.HandleFunc("/api/account/guest", s.guestLogin)
+	mux.HandleFunc("/api/account/bind", s.bind)
+	mux.HandleFunc("/api/account/password/change", s.changePassword)
+	mux.HandleFunc("/api/account/password/reset/request", s.requestReset)
+	mux.HandleFunc("/api/account/password/reset/confirm", s.confirmReset)
+	mux.HandleFunc("/api/account/refresh", s.refresh)
+	mux.HandleFunc("/api/account/logout", s.logout)
+	mux.HandleFunc("/api/account/sessions", s.listSessions)
+	mux.HandleFunc("/api/account/sessions/kick", s.kickSession)
+	mux.HandleFunc("/api/account/sessions/kick-all", s.kickAllSessions)
+	mux.HandleFunc("/api/account/export", s.export)
+	mux.HandleFunc("/api/account/delete", s.requestDeletion)
+	mux.HandleFunc("/api/account/delete/cancel", s.cancelDeletion)
+	mux.HandleFunc("/api/admin/account/ban", s.ban)
+	mux.HandleFunc("/api/admin/account/unban", s.unban)
+	mux.HandleFunc("/api/admin/account/bans", s.banHistory)
 }
 
 type credentials struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	Device   string `json:"device"`
 }
 
 func (s Service) register(w http.ResponseWriter, r *http.Request) {
@@ -51,15 +95,34 @@ func (s Service) register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	hashed, err := hashPassword(input.Password)
+	if err != nil {
+		s.logger.Printf("hash password for %s: %v", input.Username, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "could not register account"})
+		return
+	}
+
+	character, err := player.NewCharacter(input.Username, input.Username)
+	if err != nil {
+		s.logger.Printf("create character for %s: %v", input.Username, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "could not register account"})
+		return
+	}
+
 	var created dao.Account
 	s.store.WithLock(func(store *dao.DataStore) {
+		credential := usernameCredential(input.Username)
+		if _, exists := store.Credentials[credential]; exists {
+			return
+		}
 		if _, exists := store.Accounts[input.Username]; exists {
 			return
 		}
-		token := generateToken()
-		created = dao.Account{ID: input.Username, Username: input.Username, Password: input.Password, Token: token}
-		store.Accounts[input.Username] = created
-		store.Players[input.Username] = dao.Player{ID: input.Username, Name: input.Username, Level: 1, Experience: 0, LastLogin: time.Now()}
+		created = dao.Account{ID: input.Username, Username: input.Username, Password: hashed}
+		store.Accounts[created.ID] = created
+		store.Credentials[credential] = created.ID
+		character.Name = player.AvailableName(store, s.names, input.Username, "Player-"+character.ID[len(character.ID)-6:])
+		store.Players[character.ID] = character
 	})
 
 	if created.ID == "" {
@@ -68,7 +131,7 @@ func (s Service) register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	s.logger.Printf("registered user %s", created.Username)
-	writeJSON(w, http.StatusCreated, map[string]string{"token": created.Token})
+	s.issueSession(w, r, http.StatusCreated, created, input.Device)
 }
 
 func (s Service) login(w http.ResponseWriter, r *http.Request) {
@@ -83,34 +146,194 @@ func (s Service) login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ip := auth.ClientIP(r)
+	var lockout *LockoutError
+	if errors.As(s.throttle.check(input.Username, ip), &lockout) {
+		writeLockout(w, lockout)
+		return
+	}
+
 	account, err := s.authenticate(input.Username, input.Password)
+	var banned *BanError
+	if errors.As(err, &banned) {
+		writeBan(w, banned)
+		return
+	}
 	if err != nil {
+		if errors.As(s.throttle.fail(input.Username, ip), &lockout) {
+			s.logger.Printf("login locked out for %s from %s until %s", input.Username, ip, lockout.RetryAt.Format(time.RFC3339))
+			writeLockout(w, lockout)
+			return
+		}
 		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
 		return
 	}
 
-	s.cache.Set("session:"+account.ID, account.Token, 30*time.Minute)
+	s.throttle.succeed(input.Username, ip)
 	s.logger.Printf("user %s logged in", account.Username)
-	writeJSON(w, http.StatusOK, map[string]string{"token": account.Token})
+	s.issueSession(w, r, http.StatusOK, account, input.Device)
+}
+
+func (s Service) refresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	token, session, err := s.sessions.Refresh(auth.BearerToken(r), auth.ClientFromRequest(r, ""))
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tokenResponse{Token: token, ExpiresAt: session.ExpiresAt, CharacterID: session.CharacterID})
+}
+
+func (s Service) logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	session, ok := auth.SessionFrom(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "not logged in"})
+		return
+	}
+
+	s.sessions.Revoke(session)
+	if session.CharacterID != "" {
+		s.events.Publish(event.PlayerLogout, session.CharacterID, nil)
+	}
+	s.logger.Printf("user %s logged out", session.AccountID)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "logged out"})
+}
+
+func (s Service) listSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+
+	current, _ := auth.SessionFrom(r.Context())
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"current":  current.ID,
+		"sessions": s.sessions.List(current.AccountID),
+	})
+}
+
+type kickInput struct {
+	SessionID string `json:"session_id"`
+}
+
+func (s Service) kickSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	var input kickInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	current, _ := auth.SessionFrom(r.Context())
+	if err := s.sessions.Kick(current.AccountID, input.SessionID); err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.logger.Printf("user %s kicked session %s", current.AccountID, input.SessionID)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "kicked"})
+}
+
+// kickAllSessions signs the account out everywhere, including the caller.
+func (s Service) kickAllSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	current, _ := auth.SessionFrom(r.Context())
+	revoked := s.sessions.RevokeAll(current.AccountID)
+	s.logger.Printf("user %s kicked %d sessions", current.AccountID, revoked)
+	writeJSON(w, http.StatusOK, map[string]int{"revoked": revoked})
+}
+
+type tokenResponse struct {
+	Token       string    `json:"token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	CharacterID string    `json:"character_id,omitempty"`
+}
+
+// issueSession starts a session acting as the account's most recently
+// played character and publishes the login.
+func (s Service) issueSession(w http.ResponseWriter, r *http.Request, status int, account dao.Account, device string) {
+	var characterID string
+	s.store.WithRead(func(store *dao.DataStore) {
+		characterID = player.LatestCharacter(store, account.ID)
+	})
+
+	token, session, err := s.sessions.Issue(account.ID, characterID, auth.ClientFromRequest(r, device))
+	if err != nil {
+		s.logger.Printf("issue session for %s: %v", account.Username, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "could not start session"})
+		return
+	}
+	if characterID != "" {
+		s.events.Publish(event.PlayerLogin, characterID, event.PlayerLoginPayload{AccountID: account.ID})
+	}
+	writeJSON(w, status, tokenResponse{Token: token, ExpiresAt: session.ExpiresAt, CharacterID: session.CharacterID})
 }
 
 func (s Service) authenticate(username, password string) (dao.Account, error) {
 	var account dao.Account
+	var found bool
 	s.store.WithRead(func(store *dao.DataStore) {
-		if acc, ok := store.Accounts[username]; ok && acc.Password == password {
-			account = acc
+		if id, ok := store.Credentials[usernameCredential(username)]; ok {
+			account, found = store.Accounts[id]
 		}
 	})
 
-	if account.ID == "" {
+	if !found {
+		verifyPassword(dummyHash(), password)
+		return dao.Account{}, errors.New("invalid credentials")
+	}
+
+	ok, rehash := verifyPassword(account.Password, password)
+	if !ok {
 		return dao.Account{}, errors.New("invalid credentials")
 	}
 
+	if rehash {
+		s.upgradePassword(account, password)
+	}
+
+	if err := s.checkBan(account.ID); err != nil {
+		return dao.Account{}, err
+	}
+
 	return account, nil
 }
 
-func generateToken() string {
-	return time.Now().Format("20060102150405.000000000")
+// upgradePassword replaces a plaintext or outdated hash after a successful login.
+func (s Service) upgradePassword(account dao.Account, password string) {
+	hashed, err := hashPassword(password)
+	if err != nil {
+		s.logger.Printf("rehash password for %s: %v", account.Username, err)
+		return
+	}
+
+	s.store.WithLock(func(store *dao.DataStore) {
+		current, ok := store.Accounts[account.ID]
+		if !ok || current.Password != account.Password {
+			return
+		}
+		current.Password = hashed
+		store.Accounts[account.ID] = current
+	})
+	s.logger.Printf("upgraded password hash for %s", account.Username)
 }
 
 func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
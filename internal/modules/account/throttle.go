@@ -0,0 +1,185 @@
+package account
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"goworld-skeleton/internal/auth"
+	"goworld-skeleton/internal/config"
+	"goworld-skeleton/internal/dao"
+	cache "goworld-skeleton/internal/redis"
+)
+
+const (
+	failuresKeyPrefix = "login:failures:"
+	lockoutKeyPrefix  = "login:lockout:"
+)
+
+// LockoutError is returned when a username or IP is temporarily locked out.
+type LockoutError struct {
+	RetryAt time.Time
+}
+
+func (e *LockoutError) Error() string {
+	return "too many failed login attempts, retry after " + e.RetryAt.Format(time.RFC3339)
+}
+
+// loginThrottle counts failed logins per username and per client IP.
+type loginThrottle struct {
+	cache *cache.Cache
+	cfg   config.LoginThrottle
+}
+
+type throttleKey struct {
+	name      string
+	threshold int
+}
+
+func (t loginThrottle) keys(username, ip string) []throttleKey {
+	return []throttleKey{
+		{name: userKey(username), threshold: t.cfg.MaxFailuresPerUser},
+		{name: ipKey(ip), threshold: t.cfg.MaxFailuresPerIP},
+	}
+}
+
+// check returns a LockoutError while either key is locked out.
+func (t loginThrottle) check(username, ip string) error {
+	return t.locked(userKey(username), ipKey(ip))
+}
+
+// checkIP is check for logins that name no account, such as guest logins.
+func (t loginThrottle) checkIP(ip string) error {
+	return t.locked(ipKey(ip))
+}
+
+// checkGuests returns a LockoutError while ip may not create more guest
+// accounts.
+func (t loginThrottle) checkGuests(ip string) error {
+	return t.locked(guestsKey(ip))
+}
+
+// createdGuest counts a guest account created from ip and stops further
+// ones for Window once MaxGuestsPerIP is reached.
+func (t loginThrottle) createdGuest(ip string) {
+	count := t.cache.Incr(failuresKeyPrefix+guestsKey(ip), t.cfg.Window)
+	if t.cfg.MaxGuestsPerIP > 0 && count >= t.cfg.MaxGuestsPerIP {
+		t.cache.Set(lockoutKeyPrefix+guestsKey(ip), time.Now().Add(t.cfg.Window), t.cfg.Window)
+	}
+}
+
+func (t loginThrottle) locked(names ...string) error {
+	var latest time.Time
+	for _, name := range names {
+		if value, ok := t.cache.Get(lockoutKeyPrefix + name); ok {
+			if until, ok := value.(time.Time); ok && until.After(latest) {
+				latest = until
+			}
+		}
+	}
+	if latest.IsZero() {
+		return nil
+	}
+	return &LockoutError{RetryAt: latest}
+}
+
+// fail records a failed attempt and starts a lockout once a threshold is hit.
+func (t loginThrottle) fail(username, ip string) error {
+	var lockout *LockoutError
+	for _, key := range t.keys(username, ip) {
+		count := t.cache.Incr(failuresKeyPrefix+key.name, t.cfg.Window)
+		if key.threshold <= 0 || count < key.threshold {
+			continue
+		}
+
+		duration := t.backoff(count - key.threshold)
+		until := time.Now().Add(duration)
+		t.cache.Set(lockoutKeyPrefix+key.name, until, duration)
+		if lockout == nil || until.After(lockout.RetryAt) {
+			lockout = &LockoutError{RetryAt: until}
+		}
+	}
+	if lockout == nil {
+		return nil
+	}
+	return lockout
+}
+
+// succeed resets the counters after a successful login. With
+// KeepIPFailures the IP counter is instead left to expire with its window.
+func (t loginThrottle) succeed(username, ip string) {
+	names := []string{userKey(username)}
+	if !t.cfg.KeepIPFailures {
+		names = append(names, ipKey(ip))
+	}
+	for _, name := range names {
+		t.cache.Delete(failuresKeyPrefix + name)
+		t.cache.Delete(lockoutKeyPrefix + name)
+	}
+}
+
+// confirmPassword checks password against the signed-in account's current
+// one for sensitive changes. Misses count against the same per-account and
+// per-IP budget as logins, so a stolen session cannot guess the password
+// without limit. It reports whether the caller may go on, having already
+// written the response when not.
+func (s Service) confirmPassword(w http.ResponseWriter, r *http.Request, account dao.Account, password string) bool {
+	ip := auth.ClientIP(r)
+	var lockout *LockoutError
+	if errors.As(s.throttle.check(account.Username, ip), &lockout) {
+		writeLockout(w, lockout)
+		return false
+	}
+
+	if ok, _ := verifyPassword(account.Password, password); !ok {
+		if errors.As(s.throttle.fail(account.Username, ip), &lockout) {
+			s.logger.Printf("password check locked out for %s from %s until %s", account.ID, ip, lockout.RetryAt.Format(time.RFC3339))
+			writeLockout(w, lockout)
+			return false
+		}
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "password is incorrect"})
+		return false
+	}
+
+	s.throttle.succeed(account.Username, ip)
+	return true
+}
+
+func userKey(username string) string {
+	return "user:" + strings.ToLower(username)
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+func guestsKey(ip string) string {
+	return "guests:" + ip
+}
+
+func (t loginThrottle) backoff(excess int) time.Duration {
+	duration := t.cfg.BaseLockout
+	for i := 0; i < excess && duration < t.cfg.MaxLockout; i++ {
+		duration *= 2
+	}
+	if duration > t.cfg.MaxLockout {
+		duration = t.cfg.MaxLockout
+	}
+	return duration
+}
+
+func writeLockout(w http.ResponseWriter, lockout *LockoutError) {
+	seconds := int(time.Until(lockout.RetryAt).Round(time.Second) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	writeJSON(w, http.StatusTooManyRequests, map[string]string{
+		"error":    lockout.Error(),
+		"retry_at": lockout.RetryAt.Format(time.RFC3339),
+		"retry_in": fmt.Sprintf("%ds", seconds),
+	})
+}
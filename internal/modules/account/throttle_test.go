@@ -0,0 +1,126 @@
+package account
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"goworld-skeleton/internal/config"
+	cache "goworld-skeleton/internal/redis"
+)
+
+func newTestThrottle(keepIP bool) loginThrottle {
+	return loginThrottle{cache: cache.NewCache(), cfg: config.LoginThrottle{
+		MaxFailuresPerUser: 3,
+		MaxFailuresPerIP:   5,
+		KeepIPFailures:     keepIP,
+		MaxGuestsPerIP:     2,
+		Window:             time.Minute,
+		BaseLockout:        time.Second,
+		MaxLockout:         4 * time.Second,
+	}}
+}
+
+func TestThrottleLockout(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures []string
+		check    string
+		ip       string
+		locked   bool
+	}{
+		{name: "under the user threshold", failures: []string{"alice", "alice"}, check: "alice", ip: "10.0.0.1"},
+		{name: "user threshold", failures: []string{"alice", "alice", "alice"}, check: "alice", ip: "10.0.0.1", locked: true},
+		{name: "user lockout follows other IPs", failures: []string{"alice", "alice", "alice"}, check: "alice", ip: "10.0.0.2", locked: true},
+		{name: "username is case-insensitive", failures: []string{"alice", "Alice", "ALICE"}, check: "alice", ip: "10.0.0.1", locked: true},
+		{name: "other user under the IP threshold", failures: []string{"alice", "alice", "alice"}, check: "bob", ip: "10.0.0.1"},
+		{name: "IP threshold locks every user", failures: []string{"a", "b", "c", "d", "e"}, check: "bob", ip: "10.0.0.1", locked: true},
+		{name: "IP lockout stays on that IP", failures: []string{"a", "b", "c", "d", "e"}, check: "bob", ip: "10.0.0.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			th := newTestThrottle(false)
+			for _, username := range tt.failures {
+				th.fail(username, "10.0.0.1")
+			}
+			var lockout *LockoutError
+			if got := errors.As(th.check(tt.check, tt.ip), &lockout); got != tt.locked {
+				t.Errorf("check(%q, %q) locked = %v, want %v", tt.check, tt.ip, got, tt.locked)
+			}
+		})
+	}
+}
+
+func TestThrottleSucceed(t *testing.T) {
+	tests := []struct {
+		name       string
+		keepIP     bool
+		ipFailures int
+	}{
+		{name: "resets both counters", keepIP: false, ipFailures: 0},
+		{name: "keeps the IP counter", keepIP: true, ipFailures: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			th := newTestThrottle(tt.keepIP)
+			th.fail("alice", "10.0.0.1")
+			th.fail("alice", "10.0.0.1")
+			th.succeed("alice", "10.0.0.1")
+
+			if n := th.cache.Incr(failuresKeyPrefix+userKey("alice"), time.Minute) - 1; n != 0 {
+				t.Errorf("user failures after success = %d, want 0", n)
+			}
+			if n := th.cache.Incr(failuresKeyPrefix+ipKey("10.0.0.1"), time.Minute) - 1; n != tt.ipFailures {
+				t.Errorf("IP failures after success = %d, want %d", n, tt.ipFailures)
+			}
+		})
+	}
+}
+
+func TestThrottleSucceedLiftsLockout(t *testing.T) {
+	th := newTestThrottle(false)
+	for i := 0; i < 3; i++ {
+		th.fail("alice", "10.0.0.1")
+	}
+	if th.check("alice", "10.0.0.1") == nil {
+		t.Fatal("no lockout after reaching the threshold")
+	}
+	th.succeed("alice", "10.0.0.1")
+	if err := th.check("alice", "10.0.0.1"); err != nil {
+		t.Errorf("check after success = %v, want nil", err)
+	}
+}
+
+func TestThrottleBackoff(t *testing.T) {
+	th := newTestThrottle(false)
+	tests := []struct {
+		excess int
+		want   time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{2, 4 * time.Second},
+		{10, 4 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := th.backoff(tt.excess); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.excess, got, tt.want)
+		}
+	}
+}
+
+func TestThrottleGuests(t *testing.T) {
+	th := newTestThrottle(false)
+	for i := 0; i < 2; i++ {
+		if err := th.checkGuests("10.0.0.1"); err != nil {
+			t.Fatalf("guest %d: %v", i+1, err)
+		}
+		th.createdGuest("10.0.0.1")
+	}
+	if th.checkGuests("10.0.0.1") == nil {
+		t.Error("third guest from the same IP was allowed")
+	}
+	if err := th.checkGuests("10.0.0.2"); err != nil {
+		t.Errorf("guest from another IP: %v", err)
+	}
+}
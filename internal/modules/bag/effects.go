@@ -0,0 +1,151 @@
+package bag
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"goworld-skeleton/internal/dao"
+	"goworld-skeleton/internal/modules/wallet"
+)
+
+var (
+	// ErrUnknownEffect means an item names an effect nothing has registered.
+	ErrUnknownEffect = errors.New("unknown item effect")
+	// ErrNoEffect is wrapped by effects that would do nothing right now,
+	// such as healing at full health.
+	ErrNoEffect = errors.New("item would have no effect")
+)
+
+// Effect applies an item's use effect for u.Quantity items. It runs under
+// the store write lock after the items have left the bag and must either
+// apply fully or change nothing and return an error, in which case the items
+// are put back. The result is reported to the player.
+type Effect func(u *Use) (interface{}, error)
+
+// Use describes one use of an item.
+type Use struct {
+	Store    *dao.DataStore
+	PlayerID string
+	Item     dao.Item
+	Quantity int
+	Now      time.Time
+
+	after []func()
+}
+
+// After schedules fn to run once the use has committed and the store lock
+// is released, e.g. to publish events.
+func (u *Use) After(fn func()) {
+	u.after = append(u.after, fn)
+}
+
+// RegisterEffect binds an effect type to its implementation. Modules call
+// it while they are constructed, before the server starts.
+func (s Service) RegisterEffect(name string, effect Effect) {
+	s.effects[name] = effect
+}
+
+// UseItem takes quantity of an item from the player's bag and applies its
+// effect. Either both happen or neither does.
+func (s Service) UseItem(playerID, itemID string, quantity int) (interface{}, error) {
+	u := &Use{PlayerID: playerID, Quantity: quantity, Now: time.Now()}
+	var result interface{}
+	var err error
+	s.store.WithLock(func(store *dao.DataStore) {
+		result, err = s.useLocked(store, u, itemID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fn := range u.after {
+		fn()
+	}
+	s.logger.Printf("bag %s: used %s x%d", playerID, itemID, quantity)
+	return result, nil
+}
+
+// useLocked consumes the items and runs the effect, restoring the bag if the
+// effect fails. Callers must hold the store write lock.
+func (s Service) useLocked(store *dao.DataStore, u *Use, itemID string) (interface{}, error) {
+	item, ok := FindItem(store, itemID)
+	if !ok {
+		return nil, ErrUnknownItem
+	}
+	if item.Use == nil {
+		return nil, ErrNotUsable
+	}
+	effect, ok := s.effects[item.Use.Type]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEffect, item.Use.Type)
+	}
+
+	before := store.Bags[u.PlayerID]
+	if err := RemoveLocked(store, u.PlayerID, itemID, u.Quantity); err != nil {
+		return nil, err
+	}
+
+	u.Store, u.Item = store, item
+	result, err := effect(u)
+	if err != nil {
+		store.Bags[u.PlayerID] = before
+		u.after = nil
+		return nil, err
+	}
+	return result, nil
+}
+
+// openLootBox rolls the item's loot table once per box and puts the drops
+// in the bag. It fails if they do not fit.
+func (s Service) openLootBox(u *Use) (interface{}, error) {
+	total := 0
+	for _, drop := range u.Item.Use.Loot {
+		total += drop.Weight
+	}
+	if total <= 0 {
+		return nil, errors.New("loot table is empty")
+	}
+
+	counts := make(map[string]int)
+	order := make([]string, 0)
+	for i := 0; i < u.Quantity; i++ {
+		roll := rand.IntN(total)
+		for _, drop := range u.Item.Use.Loot {
+			if roll -= drop.Weight; roll < 0 {
+				if counts[drop.ItemID] == 0 {
+					order = append(order, drop.ItemID)
+				}
+				counts[drop.ItemID] += drop.Quantity
+				break
+			}
+		}
+	}
+
+	items := make([]dao.MailAttachment, 0, len(order))
+	for _, itemID := range order {
+		items = append(items, dao.MailAttachment{ItemID: itemID, Quantity: counts[itemID]})
+	}
+	result, err := s.GiveLocked(u.Store, u.PlayerID, Grant{Items: items, Overflow: OverflowReject}, u.Now)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"loot": result.Added}, nil
+}
+
+// grantCurrency credits the item's currency amount per item used.
+func (s Service) grantCurrency(u *Use) (interface{}, error) {
+	amount := u.Item.Use.Amount * int64(u.Quantity)
+	meta := wallet.Meta{Reason: "use " + u.Item.ID, Source: "bag"}
+	entries, err := wallet.ApplyLocked(u.Store, u.PlayerID, []wallet.Change{{Currency: u.Item.Use.Currency, Amount: amount}}, meta, u.Now)
+	if err != nil {
+		return nil, err
+	}
+	u.After(func() {
+		for _, e := range entries {
+			s.logger.Printf("wallet %s %s %+d -> %d (%s/%s)", u.PlayerID, e.Currency, e.Delta, e.Balance, meta.Source, meta.Reason)
+		}
+	})
+	return map[string]interface{}{"ledger": entries}, nil
+}
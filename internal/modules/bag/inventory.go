@@ -0,0 +1,184 @@
+package bag
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"goworld-skeleton/internal/dao"
+)
+
+// Overflow policies decide what happens to items that do not fit.
+const (
+	// OverflowReject fails the whole grant and leaves the bag untouched.
+	OverflowReject = "reject"
+	// OverflowMail fills the bag and mails the rest to the player.
+	OverflowMail = "mail"
+)
+
+var (
+	ErrPlayerNotFound  = errors.New("player not found")
+	ErrUnknownItem     = errors.New("unknown item")
+	ErrInvalidQuantity = errors.New("quantity must be positive")
+	ErrBagFull         = errors.New("not enough bag space")
+	ErrNotEnough       = errors.New("not enough items")
+	ErrNotUsable       = errors.New("item cannot be used")
+	ErrUnknownPolicy   = errors.New("overflow must be reject or mail")
+)
+
+// Grant is a set of items given to a player. Overflow defaults to
+// OverflowReject. MailID, Subject and Body label the overflow mail.
+type Grant struct {
+	Items    []dao.MailAttachment
+	Overflow string
+	MailID   string
+	Subject  string
+	Body     string
+}
+
+// Result reports where granted items went.
+type Result struct {
+	Added  []dao.MailAttachment `json:"added"`
+	Mailed []dao.MailAttachment `json:"mailed,omitempty"`
+}
+
+// Give adds items to the player's bag. Every item grant goes through here.
+func (s Service) Give(playerID string, g Grant) (Result, error) {
+	var result Result
+	var err error
+	s.store.WithLock(func(store *dao.DataStore) {
+		result, err = s.GiveLocked(store, playerID, g, time.Now())
+	})
+	if err == nil {
+		s.logger.Printf("bag %s: added %v, mailed %v", playerID, result.Added, result.Mailed)
+	}
+	return result, err
+}
+
+// GiveLocked is Give for callers that already hold the store write lock,
+// so a grant can commit together with their own changes. Items top up
+// existing stacks before taking the lowest free slots. On error nothing is
+// changed.
+func (s Service) GiveLocked(store *dao.DataStore, playerID string, g Grant, now time.Time) (Result, error) {
+	if p, ok := store.Players[playerID]; !ok || p.Deleted() {
+		return Result{}, ErrPlayerNotFound
+	}
+	if g.Overflow == "" {
+		g.Overflow = OverflowReject
+	}
+	if g.Overflow != OverflowReject && g.Overflow != OverflowMail {
+		return Result{}, ErrUnknownPolicy
+	}
+
+	bag := append([]dao.BagEntry(nil), store.Bags[playerID]...)
+	result := Result{Added: make([]dao.MailAttachment, 0, len(g.Items))}
+	for _, grant := range g.Items {
+		if grant.Quantity <= 0 {
+			return Result{}, ErrInvalidQuantity
+		}
+		item, ok := FindItem(store, grant.ItemID)
+		if !ok {
+			return Result{}, fmt.Errorf("%w: %s", ErrUnknownItem, grant.ItemID)
+		}
+
+		limit := stackLimit(item)
+		left := grant.Quantity
+		for i := range bag {
+			if left == 0 {
+				break
+			}
+			if bag[i].ItemID == item.ID && bag[i].Quantity < limit {
+				n := min(left, limit-bag[i].Quantity)
+				bag[i].Quantity += n
+				left -= n
+			}
+		}
+		for left > 0 && len(bag) < s.capacity {
+			n := min(left, limit)
+			bag = append(bag, dao.BagEntry{Slot: freeSlot(bag), ItemID: item.ID, Quantity: n})
+			left -= n
+		}
+
+		if added := grant.Quantity - left; added > 0 {
+			result.Added = append(result.Added, dao.MailAttachment{ItemID: item.ID, Quantity: added})
+		}
+		if left > 0 {
+			result.Mailed = append(result.Mailed, dao.MailAttachment{ItemID: item.ID, Quantity: left})
+		}
+	}
+
+	if len(result.Mailed) > 0 {
+		if g.Overflow == OverflowReject {
+			return Result{}, ErrBagFull
+		}
+		mail := dao.Mail{ID: g.MailID, Subject: g.Subject, Body: g.Body, Attachments: result.Mailed}
+		if mail.ID == "" {
+			mail.ID = fmt.Sprintf("bag-%s-%d", playerID, now.UnixNano())
+		}
+		if mail.Subject == "" {
+			mail.Subject = "背包已满"
+			mail.Body = "背包空间不足，未能放入的物品已随邮件发放。"
+		}
+		store.Mails[playerID] = append(store.Mails[playerID], mail)
+	}
+
+	storeBag(store, playerID, bag)
+	return result, nil
+}
+
+// RemoveLocked takes quantity of an item out of the player's bag, emptying
+// the stacks in the highest slots first. The other stacks keep their slots.
+// Callers must hold the store write lock.
+func RemoveLocked(store *dao.DataStore, playerID, itemID string, quantity int) error {
+	if quantity <= 0 {
+		return ErrInvalidQuantity
+	}
+	if Count(store, playerID, itemID) < quantity {
+		return ErrNotEnough
+	}
+
+	bag := append([]dao.BagEntry(nil), store.Bags[playerID]...)
+	for i := len(bag) - 1; i >= 0 && quantity > 0; i-- {
+		if bag[i].ItemID != itemID {
+			continue
+		}
+		n := min(quantity, bag[i].Quantity)
+		bag[i].Quantity -= n
+		quantity -= n
+	}
+
+	kept := bag[:0]
+	for _, entry := range bag {
+		if entry.Quantity > 0 {
+			kept = append(kept, entry)
+		}
+	}
+	storeBag(store, playerID, kept)
+	return nil
+}
+
+// Count returns how many of an item the player holds across all stacks.
+// Callers must hold the store lock.
+func Count(store *dao.DataStore, playerID, itemID string) int {
+	total := 0
+	for _, entry := range store.Bags[playerID] {
+		if entry.ItemID == itemID {
+			total += entry.Quantity
+		}
+	}
+	return total
+}
+
+func stackLimit(item dao.Item) int {
+	return max(item.MaxStack, 1)
+}
+
+// FindItem looks up an item definition. Callers must hold the store lock.
+func FindItem(store *dao.DataStore, id string) (dao.Item, bool) {
+	for _, item := range store.Items {
+		if item.ID == id {
+			return item, true
+		}
+	}
+	return dao.Item{}, false
+}
@@ -0,0 +1,280 @@
+package bag
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+
+	"goworld-skeleton/internal/auth"
+	"goworld-skeleton/internal/dao"
+)
+
+// Sort orders for Sort.
+const (
+	// SortByType groups equipment by slot, then consumables, then the rest.
+	SortByType = "type"
+	// SortByRarity puts the rarest items first.
+	SortByRarity = "rarity"
+	// SortByID orders by item ID.
+	SortByID = "id"
+)
+
+var (
+	ErrInvalidSlot = errors.New("slot out of range")
+	ErrEmptySlot   = errors.New("slot is empty")
+	ErrSlotTaken   = errors.New("target slot is not empty")
+	ErrStaleBag    = errors.New("bag has changed, reload it")
+	ErrUnknownSort = errors.New("sort must be type, rarity or id")
+)
+
+// rarityRank orders rarities from least to most rare.
+var rarityRank = map[string]int{"common": 0, "uncommon": 1, "rare": 2, "epic": 3, "legendary": 4}
+
+// typeRank orders item types for SortByType.
+var typeRank = map[string]int{dao.SlotWeapon: 0, dao.SlotArmor: 1, dao.SlotAccessory: 2}
+
+func itemType(item dao.Item) int {
+	if rank, ok := typeRank[item.Slot]; ok {
+		return rank
+	}
+	if item.Use != nil {
+		return len(typeRank)
+	}
+	return len(typeRank) + 1
+}
+
+// storeBag saves the player's bag ordered by slot and bumps its revision.
+// Callers must hold the store write lock.
+func storeBag(store *dao.DataStore, playerID string, bag []dao.BagEntry) {
+	sort.Slice(bag, func(i, j int) bool { return bag[i].Slot < bag[j].Slot })
+	store.Bags[playerID] = bag
+	store.BagRevisions[playerID]++
+}
+
+// freeSlot returns the lowest slot no entry of bag uses.
+func freeSlot(bag []dao.BagEntry) int {
+	used := make(map[int]bool, len(bag))
+	for _, entry := range bag {
+		used[entry.Slot] = true
+	}
+	slot := 0
+	for used[slot] {
+		slot++
+	}
+	return slot
+}
+
+func slotIndex(bag []dao.BagEntry, slot int) int {
+	for i, entry := range bag {
+		if entry.Slot == slot {
+			return i
+		}
+	}
+	return -1
+}
+
+// merge tops up partial stacks from later ones of the same item, in slot
+// order. Stacks that keep any items stay in their slots.
+func merge(store *dao.DataStore, bag []dao.BagEntry) []dao.BagEntry {
+	for i := range bag {
+		item, _ := FindItem(store, bag[i].ItemID)
+		limit := stackLimit(item)
+		for j := i + 1; j < len(bag) && bag[i].Quantity < limit; j++ {
+			if bag[j].ItemID != bag[i].ItemID || bag[j].Quantity == 0 {
+				continue
+			}
+			n := min(bag[j].Quantity, limit-bag[i].Quantity)
+			bag[i].Quantity += n
+			bag[j].Quantity -= n
+		}
+	}
+
+	kept := bag[:0]
+	for _, entry := range bag {
+		if entry.Quantity > 0 {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// arrange merges the bag and lays it out from slot zero in the given order.
+func arrange(store *dao.DataStore, bag []dao.BagEntry, by string) ([]dao.BagEntry, error) {
+	if by == "" {
+		by = SortByType
+	}
+	if by != SortByType && by != SortByRarity && by != SortByID {
+		return nil, ErrUnknownSort
+	}
+
+	bag = merge(store, bag)
+	items := make(map[string]dao.Item, len(bag))
+	for _, entry := range bag {
+		items[entry.ItemID], _ = FindItem(store, entry.ItemID)
+	}
+	sort.SliceStable(bag, func(i, j int) bool {
+		a, b := items[bag[i].ItemID], items[bag[j].ItemID]
+		if by == SortByType && itemType(a) != itemType(b) {
+			return itemType(a) < itemType(b)
+		}
+		if by != SortByID && rarityRank[a.Rarity] != rarityRank[b.Rarity] {
+			return rarityRank[a.Rarity] > rarityRank[b.Rarity]
+		}
+		if by == SortByRarity && itemType(a) != itemType(b) {
+			return itemType(a) < itemType(b)
+		}
+		if a.ID != b.ID {
+			return a.ID < b.ID
+		}
+		return bag[i].Quantity > bag[j].Quantity
+	})
+	for i := range bag {
+		bag[i].Slot = i
+	}
+	return bag, nil
+}
+
+// split moves quantity out of the stack in from into slot to, or into the
+// lowest free slot when to is nil.
+func (s Service) split(bag []dao.BagEntry, from int, to *int, quantity int) ([]dao.BagEntry, error) {
+	src := slotIndex(bag, from)
+	if src < 0 {
+		return nil, ErrEmptySlot
+	}
+	if quantity <= 0 || quantity >= bag[src].Quantity {
+		return nil, ErrInvalidQuantity
+	}
+	if len(bag) >= s.capacity {
+		return nil, ErrBagFull
+	}
+
+	target := freeSlot(bag)
+	if to != nil {
+		target = *to
+		if target < 0 || target >= s.capacity {
+			return nil, ErrInvalidSlot
+		}
+		if slotIndex(bag, target) >= 0 {
+			return nil, ErrSlotTaken
+		}
+	}
+
+	bag[src].Quantity -= quantity
+	return append(bag, dao.BagEntry{Slot: target, ItemID: bag[src].ItemID, Quantity: quantity}), nil
+}
+
+// move puts the stack in from into slot to. Onto the same item it merges as
+// much as fits; onto anything else the two stacks swap.
+func (s Service) move(store *dao.DataStore, bag []dao.BagEntry, from int, to *int) ([]dao.BagEntry, error) {
+	if to == nil || *to < 0 || *to >= s.capacity {
+		return nil, ErrInvalidSlot
+	}
+	src := slotIndex(bag, from)
+	if src < 0 {
+		return nil, ErrEmptySlot
+	}
+	if from == *to {
+		return bag, nil
+	}
+
+	dst := slotIndex(bag, *to)
+	if dst < 0 {
+		bag[src].Slot = *to
+		return bag, nil
+	}
+
+	item, _ := FindItem(store, bag[src].ItemID)
+	if room := stackLimit(item) - bag[dst].Quantity; bag[dst].ItemID == bag[src].ItemID && room > 0 {
+		n := min(room, bag[src].Quantity)
+		bag[dst].Quantity += n
+		bag[src].Quantity -= n
+		if bag[src].Quantity == 0 {
+			bag = append(bag[:src], bag[src+1:]...)
+		}
+		return bag, nil
+	}
+
+	bag[src].Slot, bag[dst].Slot = bag[dst].Slot, bag[src].Slot
+	return bag, nil
+}
+
+type layoutInput struct {
+	PlayerID string `json:"player_id"`
+	// Revision, if set, must match the bag's current revision.
+	Revision *int64 `json:"revision"`
+	By       string `json:"by"`
+	From     int    `json:"from"`
+	To       *int   `json:"to"`
+	Quantity int    `json:"quantity"`
+}
+
+// sortBag handles POST /api/bag/sort with {"by": "type"|"rarity"|"id"}.
+func (s Service) sortBag(w http.ResponseWriter, r *http.Request) {
+	s.rearrange(w, r, func(store *dao.DataStore, bag []dao.BagEntry, input layoutInput) ([]dao.BagEntry, error) {
+		return arrange(store, bag, input.By)
+	})
+}
+
+// mergeBag handles POST /api/bag/merge, combining partial stacks in place.
+func (s Service) mergeBag(w http.ResponseWriter, r *http.Request) {
+	s.rearrange(w, r, func(store *dao.DataStore, bag []dao.BagEntry, _ layoutInput) ([]dao.BagEntry, error) {
+		return merge(store, bag), nil
+	})
+}
+
+// splitStack handles POST /api/bag/split with {"from", "quantity", "to"}.
+func (s Service) splitStack(w http.ResponseWriter, r *http.Request) {
+	s.rearrange(w, r, func(_ *dao.DataStore, bag []dao.BagEntry, input layoutInput) ([]dao.BagEntry, error) {
+		return s.split(bag, input.From, input.To, input.Quantity)
+	})
+}
+
+// moveStack handles POST /api/bag/move with {"from", "to"}.
+func (s Service) moveStack(w http.ResponseWriter, r *http.Request) {
+	s.rearrange(w, r, func(store *dao.DataStore, bag []dao.BagEntry, input layoutInput) ([]dao.BagEntry, error) {
+		return s.move(store, bag, input.From, input.To)
+	})
+}
+
+// rearrange runs change on a copy of the caller's bag under the store lock
+// and saves the result, rejecting requests made against an old revision.
+func (s Service) rearrange(w http.ResponseWriter, r *http.Request, change func(*dao.DataStore, []dao.BagEntry, layoutInput) ([]dao.BagEntry, error)) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	var input layoutInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	playerID, err := auth.ResolvePlayer(r, input.PlayerID)
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var view map[string]interface{}
+	s.store.WithLock(func(store *dao.DataStore) {
+		if input.Revision != nil && *input.Revision != store.BagRevisions[playerID] {
+			err = ErrStaleBag
+			return
+		}
+		var bag []dao.BagEntry
+		if bag, err = change(store, append([]dao.BagEntry(nil), store.Bags[playerID]...), input); err != nil {
+			return
+		}
+		storeBag(store, playerID, bag)
+		view = s.view(store, playerID)
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.logger.Printf("bag %s: %s", playerID, r.URL.Path)
+	writeJSON(w, http.StatusOK, view)
+}
@@ -2,27 +2,44 @@ package bag
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strings"
 
+	"goworld-skeleton/internal/auth"
 	"goworld-skeleton/internal/dao"
 )
 
 // Service exposes bag operations.
 type Service struct {
-	store  *dao.DataStore
-	logger *log.Logger
+	store    *dao.DataStore
+	capacity int
+	effects  map[string]Effect
+	logger   *log.Logger
 }
 
-// NewService constructs a bag service.
-func NewService(store *dao.DataStore, logger *log.Logger) Service {
-	return Service{store: store, logger: logger}
+// NewService constructs a bag service; capacity is the slot count per bag.
+// Loot boxes and currency items work out of the box; other effects are
+// registered by the modules that own them.
+func NewService(store *dao.DataStore, capacity int, logger *log.Logger) Service {
+	s := Service{store: store, capacity: capacity, effects: map[string]Effect{}, logger: logger}
+	s.RegisterEffect(dao.EffectLootBox, s.openLootBox)
+	s.RegisterEffect(dao.EffectGrantCurrency, s.grantCurrency)
+	return s
 }
 
 // Register binds HTTP endpoints.
 func (s Service) Register(mux *http.ServeMux) {
 	mux.HandleFunc("/api/bag/", s.getBag)
+	mux.HandleFunc("/api/bag/use", s.use)
+	mux.HandleFunc("/api/bag/discard", s.discard)
+	mux.HandleFunc("/api/bag/sort", s.sortBag)
+	mux.HandleFunc("/api/bag/merge", s.mergeBag)
+	mux.HandleFunc("/api/bag/split", s.splitStack)
+	mux.HandleFunc("/api/bag/move", s.moveStack)
+	mux.HandleFunc("/api/admin/bag/add", s.adminAdd)
+	mux.HandleFunc("/api/admin/bag/remove", s.adminRemove)
 }
 
 func (s Service) getBag(w http.ResponseWriter, r *http.Request) {
@@ -31,19 +48,185 @@ func (s Service) getBag(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	playerID := strings.TrimPrefix(r.URL.Path, "/api/bag/")
-	var bag []dao.BagEntry
+	playerID, err := auth.ResolvePlayer(r, strings.TrimPrefix(r.URL.Path, "/api/bag/"))
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+	var view map[string]interface{}
 	s.store.WithRead(func(store *dao.DataStore) {
-		bag = store.Bags[playerID]
+		view = s.view(store, playerID)
 	})
 
-	if bag == nil {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "bag not found"})
+	s.logger.Printf("bag fetched for %s", playerID)
+	writeJSON(w, http.StatusOK, view)
+}
+
+// view is the response body describing a bag. Callers must hold the store
+// lock.
+func (s Service) view(store *dao.DataStore, playerID string) map[string]interface{} {
+	bag := append(make([]dao.BagEntry, 0), store.Bags[playerID]...)
+	return map[string]interface{}{"items": bag, "capacity": s.capacity, "used": len(bag), "revision": store.BagRevisions[playerID]}
+}
+
+type itemInput struct {
+	PlayerID string `json:"player_id"`
+	ItemID   string `json:"item_id"`
+	Quantity int    `json:"quantity"`
+	Reason   string `json:"reason"`
+}
+
+// use consumes items from the caller's bag and applies their effect.
+func (s Service) use(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
 		return
 	}
 
-	s.logger.Printf("bag fetched for %s", playerID)
-	writeJSON(w, http.StatusOK, map[string]interface{}{"items": bag})
+	var input itemInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if input.Quantity == 0 {
+		input.Quantity = 1
+	}
+
+	playerID, err := auth.ResolvePlayer(r, input.PlayerID)
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+
+	result, err := s.UseItem(playerID, input.ItemID, input.Quantity)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var view map[string]interface{}
+	s.store.WithRead(func(store *dao.DataStore) {
+		view = s.view(store, playerID)
+	})
+	view["effect"] = result
+	writeJSON(w, http.StatusOK, view)
+}
+
+// discard throws items away.
+func (s Service) discard(w http.ResponseWriter, r *http.Request) {
+	s.take(w, r, func(store *dao.DataStore, playerID string, input itemInput) error {
+		return RemoveLocked(store, playerID, input.ItemID, input.Quantity)
+	})
+}
+
+// take decodes an itemInput for the caller, defaulting quantity to one, and
+// applies remove under the store lock.
+func (s Service) take(w http.ResponseWriter, r *http.Request, remove func(*dao.DataStore, string, itemInput) error) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	var input itemInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if input.Quantity == 0 {
+		input.Quantity = 1
+	}
+
+	playerID, err := auth.ResolvePlayer(r, input.PlayerID)
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var view map[string]interface{}
+	s.store.WithLock(func(store *dao.DataStore) {
+		if err = remove(store, playerID, input); err == nil {
+			view = s.view(store, playerID)
+		}
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.logger.Printf("bag %s: %s x%d removed (%s)", playerID, input.ItemID, input.Quantity, r.URL.Path)
+	writeJSON(w, http.StatusOK, view)
+}
+
+type addInput struct {
+	PlayerID string               `json:"player_id"`
+	Items    []dao.MailAttachment `json:"items"`
+	Overflow string               `json:"overflow"`
+	Reason   string               `json:"reason"`
+}
+
+// adminAdd lets ops grant items; overflow is "reject" (default) or "mail".
+func (s Service) adminAdd(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	var input addInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	result, err := s.Give(input.PlayerID, Grant{Items: input.Items, Overflow: input.Overflow, Subject: "系统发放", Body: input.Reason})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.logger.Printf("bag %s: %s granted %v (%s)", input.PlayerID, auth.Operator(r), input.Items, input.Reason)
+	writeJSON(w, http.StatusOK, result)
+}
+
+// adminRemove lets ops take items out of a player's bag.
+func (s Service) adminRemove(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	var input itemInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var err error
+	s.store.WithLock(func(store *dao.DataStore) {
+		if p, ok := store.Players[input.PlayerID]; !ok || p.Deleted() {
+			err = ErrPlayerNotFound
+			return
+		}
+		err = RemoveLocked(store, input.PlayerID, input.ItemID, input.Quantity)
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.logger.Printf("bag %s: %s removed %s x%d (%s)", input.PlayerID, auth.Operator(r), input.ItemID, input.Quantity, input.Reason)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "removed"})
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrPlayerNotFound), errors.Is(err, ErrUnknownItem):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, ErrBagFull), errors.Is(err, ErrNotEnough), errors.Is(err, ErrNoEffect),
+		errors.Is(err, ErrEmptySlot), errors.Is(err, ErrSlotTaken), errors.Is(err, ErrStaleBag):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
 }
 
 func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
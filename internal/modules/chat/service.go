@@ -6,18 +6,22 @@ import (
 	"net/http"
 	"time"
 
+	"goworld-skeleton/internal/auth"
 	"goworld-skeleton/internal/dao"
+	"goworld-skeleton/internal/event"
+	"goworld-skeleton/internal/modules/friend"
 )
 
 // Service exposes chat operations.
 type Service struct {
 	store  *dao.DataStore
+	events *event.Bus
 	logger *log.Logger
 }
 
 // NewService constructs a chat service.
-func NewService(store *dao.DataStore, logger *log.Logger) Service {
-	return Service{store: store, logger: logger}
+func NewService(store *dao.DataStore, events *event.Bus, logger *log.Logger) Service {
+	return Service{store: store, events: events, logger: logger}
 }
 
 // Register binds HTTP endpoints.
@@ -52,8 +56,14 @@ func (s Service) send(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	from, err := auth.ResolvePlayer(r, input.From)
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+
 	msg := dao.ChatMessage{
-		From:    input.From,
+		From:    from,
 		To:      input.To,
 		RoomID:  input.RoomID,
 		Body:    input.Body,
@@ -61,17 +71,36 @@ func (s Service) send(w http.ResponseWriter, r *http.Request) {
 		SentAt:  time.Now(),
 	}
 
+	blocked := false
 	s.store.WithLock(func(store *dao.DataStore) {
+		if msg.To != "" && friend.Blocked(store, msg.To, msg.From) {
+			blocked = true
+			return
+		}
 		store.Chats = append(store.Chats, msg)
 	})
+	if blocked {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": friend.ErrBlocked.Error()})
+		return
+	}
 
 	s.logger.Printf("chat message recorded from %s", msg.From)
+	s.events.Publish(event.ChatSent, msg.From, event.ChatPayload{Channel: msg.Channel, To: msg.To, RoomID: msg.RoomID})
 	writeJSON(w, http.StatusCreated, msg)
 }
 
-func (s Service) history(w http.ResponseWriter, _ *http.Request) {
+// history returns public and room messages plus private messages the
+// caller sent or received.
+func (s Service) history(w http.ResponseWriter, r *http.Request) {
+	playerID := auth.PlayerID(r)
 	var history []dao.ChatMessage
-	s.store.WithRead(func(store *dao.DataStore) { history = append(history, store.Chats...) })
+	s.store.WithRead(func(store *dao.DataStore) {
+		for _, msg := range store.Chats {
+			if msg.To == "" || msg.To == playerID || msg.From == playerID {
+				history = append(history, msg)
+			}
+		}
+	})
 	writeJSON(w, http.StatusOK, map[string]interface{}{"messages": history})
 }
 
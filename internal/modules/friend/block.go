@@ -0,0 +1,62 @@
+package friend
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"goworld-skeleton/internal/auth"
+	"goworld-skeleton/internal/dao"
+)
+
+// Blocked reports whether recipient has blocked sender. Chat and room
+// invites use it to drop unwanted contact. Callers must hold the store lock.
+func Blocked(store *dao.DataStore, recipient, sender string) bool {
+	return slices.Contains(store.Blocks[recipient], sender)
+}
+
+// blocks lists the players the caller has blocked.
+func (s Service) blocks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+
+	playerID, err := auth.ResolvePlayer(r, "")
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+
+	blocked := make([]string, 0)
+	s.store.WithRead(func(store *dao.DataStore) {
+		blocked = append(blocked, store.Blocks[playerID]...)
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{"blocked": blocked})
+}
+
+// block stops player_id from messaging, inviting or befriending the caller.
+// Any friendship or pending request between the two is removed.
+func (s Service) block(w http.ResponseWriter, r *http.Request) {
+	s.handle(w, r, func(store *dao.DataStore, self, target string, _ time.Time) (string, error) {
+		if Blocked(store, self, target) {
+			return "", ErrAlreadyBlocked
+		}
+		unfriend(store, self, target)
+		dropRequest(store, self, target)
+		dropRequest(store, target, self)
+		store.Blocks[self] = append(store.Blocks[self], target)
+		return "blocked", nil
+	})
+}
+
+// unblock lifts a block.
+func (s Service) unblock(w http.ResponseWriter, r *http.Request) {
+	s.handle(w, r, func(store *dao.DataStore, self, target string, _ time.Time) (string, error) {
+		if !Blocked(store, self, target) {
+			return "", ErrNotBlocked
+		}
+		store.Blocks[self] = slices.DeleteFunc(store.Blocks[self], func(id string) bool { return id == target })
+		return "unblocked", nil
+	})
+}
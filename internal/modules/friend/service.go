@@ -0,0 +1,310 @@
+package friend
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"goworld-skeleton/internal/auth"
+	"goworld-skeleton/internal/dao"
+	"goworld-skeleton/internal/presence"
+)
+
+var (
+	ErrPlayerNotFound = errors.New("player not found")
+	ErrSelf           = errors.New("cannot target yourself")
+	ErrAlreadyFriends = errors.New("already friends")
+	ErrAlreadyPending = errors.New("friend request already sent")
+	ErrNoRequest      = errors.New("no friend request from that player")
+	ErrNotFriends     = errors.New("not friends")
+	ErrFriendLimit    = errors.New("friend list is full")
+	ErrTargetFull     = errors.New("that player's friend list is full")
+	ErrBlocked        = errors.New("player is not accepting contact from you")
+	ErrBlockedByYou   = errors.New("unblock the player first")
+	ErrAlreadyBlocked = errors.New("player already blocked")
+	ErrNotBlocked     = errors.New("player is not blocked")
+	ErrNoTarget       = errors.New("player_id is required")
+)
+
+// Service exposes friends, friend requests and the block list.
+type Service struct {
+	store    *dao.DataStore
+	presence *presence.Tracker
+	limit    int
+	logger   *log.Logger
+}
+
+// NewService constructs a friend service; limit caps each friend list.
+func NewService(store *dao.DataStore, tracker *presence.Tracker, limit int, logger *log.Logger) Service {
+	return Service{store: store, presence: tracker, limit: limit, logger: logger}
+}
+
+// Register binds HTTP endpoints.
+func (s Service) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/api/friend", s.list)
+	mux.HandleFunc("/api/friend/requests", s.requests)
+	mux.HandleFunc("/api/friend/request", s.sendRequest)
+	mux.HandleFunc("/api/friend/accept", s.accept)
+	mux.HandleFunc("/api/friend/reject", s.reject)
+	mux.HandleFunc("/api/friend/remove", s.remove)
+	mux.HandleFunc("/api/friend/blocks", s.blocks)
+	mux.HandleFunc("/api/friend/block", s.block)
+	mux.HandleFunc("/api/friend/unblock", s.unblock)
+}
+
+// Entry is a friend as shown in the friend list.
+type Entry struct {
+	PlayerID string    `json:"player_id"`
+	Name     string    `json:"name"`
+	Level    int       `json:"level"`
+	State    string    `json:"state"`
+	RoomID   string    `json:"room_id,omitempty"`
+	LastSeen time.Time `json:"last_seen"`
+	Since    time.Time `json:"since"`
+}
+
+type targetInput struct {
+	PlayerID string `json:"player_id"`
+}
+
+// list shows the caller's friends with level and presence.
+func (s Service) list(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+
+	playerID, err := auth.ResolvePlayer(r, "")
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+
+	entries := make([]Entry, 0)
+	s.store.WithRead(func(store *dao.DataStore) {
+		for _, f := range store.Friends[playerID] {
+			p := store.Players[f.PlayerID]
+			entries = append(entries, Entry{PlayerID: f.PlayerID, Name: p.Name, Level: p.Level, Since: f.Since})
+		}
+	})
+	for i := range entries {
+		status := s.presence.Get(entries[i].PlayerID)
+		entries[i].State = status.State
+		entries[i].RoomID = status.RoomID
+		entries[i].LastSeen = status.LastSeen
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"friends": entries, "limit": s.limit})
+}
+
+// requests lists pending requests the caller received and sent.
+func (s Service) requests(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+
+	playerID, err := auth.ResolvePlayer(r, "")
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+
+	incoming := make([]dao.FriendRequest, 0)
+	outgoing := make([]map[string]interface{}, 0)
+	s.store.WithRead(func(store *dao.DataStore) {
+		incoming = append(incoming, store.FriendRequests[playerID]...)
+		for to, pending := range store.FriendRequests {
+			for _, req := range pending {
+				if req.From == playerID {
+					outgoing = append(outgoing, map[string]interface{}{"to": to, "sent_at": req.SentAt})
+				}
+			}
+		}
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{"incoming": incoming, "outgoing": outgoing})
+}
+
+// sendRequest asks another player to be friends. A request to someone who
+// already asked the caller accepts theirs instead.
+func (s Service) sendRequest(w http.ResponseWriter, r *http.Request) {
+	s.handle(w, r, func(store *dao.DataStore, from, to string, now time.Time) (string, error) {
+		switch {
+		case isFriend(store, from, to):
+			return "", ErrAlreadyFriends
+		case Blocked(store, from, to):
+			return "", ErrBlockedByYou
+		case Blocked(store, to, from):
+			return "", ErrBlocked
+		case pendingIndex(store, to, from) >= 0:
+			return "", ErrAlreadyPending
+		}
+		if pendingIndex(store, from, to) >= 0 {
+			if err := s.befriend(store, from, to, now); err != nil {
+				return "", err
+			}
+			return "accepted", nil
+		}
+		if len(store.Friends[from]) >= s.limit {
+			return "", ErrFriendLimit
+		}
+		store.FriendRequests[to] = append(store.FriendRequests[to], dao.FriendRequest{From: from, SentAt: now})
+		return "requested", nil
+	})
+}
+
+// accept turns a pending request from player_id into a friendship.
+func (s Service) accept(w http.ResponseWriter, r *http.Request) {
+	s.handle(w, r, func(store *dao.DataStore, self, from string, now time.Time) (string, error) {
+		if pendingIndex(store, self, from) < 0 {
+			return "", ErrNoRequest
+		}
+		if err := s.befriend(store, self, from, now); err != nil {
+			return "", err
+		}
+		return "accepted", nil
+	})
+}
+
+// reject discards a pending request from player_id.
+func (s Service) reject(w http.ResponseWriter, r *http.Request) {
+	s.handle(w, r, func(store *dao.DataStore, self, from string, _ time.Time) (string, error) {
+		if !dropRequest(store, self, from) {
+			return "", ErrNoRequest
+		}
+		return "rejected", nil
+	})
+}
+
+// remove ends a friendship on both sides.
+func (s Service) remove(w http.ResponseWriter, r *http.Request) {
+	s.handle(w, r, func(store *dao.DataStore, self, other string, _ time.Time) (string, error) {
+		if !isFriend(store, self, other) {
+			return "", ErrNotFriends
+		}
+		unfriend(store, self, other)
+		return "removed", nil
+	})
+}
+
+// handle runs a POST {"player_id": ...} action between the caller and the
+// target under the store lock and reports the resulting status.
+func (s Service) handle(w http.ResponseWriter, r *http.Request, action func(store *dao.DataStore, self, target string, now time.Time) (string, error)) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	self, err := auth.ResolvePlayer(r, "")
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var input targetInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var status string
+	switch {
+	case input.PlayerID == "":
+		err = ErrNoTarget
+	case input.PlayerID == self:
+		err = ErrSelf
+	default:
+		s.store.WithLock(func(store *dao.DataStore) {
+			if p, ok := store.Players[input.PlayerID]; !ok || p.Deleted() {
+				err = ErrPlayerNotFound
+				return
+			}
+			status, err = action(store, self, input.PlayerID, time.Now())
+		})
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.logger.Printf("friend %s: %s -> %s", status, self, input.PlayerID)
+	writeJSON(w, http.StatusOK, map[string]string{"status": status, "player_id": input.PlayerID})
+}
+
+// befriend records a friendship on both sides and drops the requests between
+// the two players. Callers must hold the store write lock.
+func (s Service) befriend(store *dao.DataStore, a, b string, now time.Time) error {
+	if len(store.Friends[a]) >= s.limit {
+		return ErrFriendLimit
+	}
+	if len(store.Friends[b]) >= s.limit {
+		return ErrTargetFull
+	}
+	dropRequest(store, a, b)
+	dropRequest(store, b, a)
+	store.Friends[a] = append(store.Friends[a], dao.Friend{PlayerID: b, Since: now})
+	store.Friends[b] = append(store.Friends[b], dao.Friend{PlayerID: a, Since: now})
+	return nil
+}
+
+func isFriend(store *dao.DataStore, a, b string) bool {
+	return slices.ContainsFunc(store.Friends[a], func(f dao.Friend) bool { return f.PlayerID == b })
+}
+
+func unfriend(store *dao.DataStore, a, b string) {
+	store.Friends[a] = slices.DeleteFunc(store.Friends[a], func(f dao.Friend) bool { return f.PlayerID == b })
+	store.Friends[b] = slices.DeleteFunc(store.Friends[b], func(f dao.Friend) bool { return f.PlayerID == a })
+}
+
+// pendingIndex returns the position of from's request in to's inbox, or -1.
+func pendingIndex(store *dao.DataStore, to, from string) int {
+	return slices.IndexFunc(store.FriendRequests[to], func(req dao.FriendRequest) bool { return req.From == from })
+}
+
+func dropRequest(store *dao.DataStore, to, from string) bool {
+	i := pendingIndex(store, to, from)
+	if i < 0 {
+		return false
+	}
+	store.FriendRequests[to] = slices.Delete(store.FriendRequests[to], i, i+1)
+	return true
+}
+
+// Forget removes the player from every friend list, request and block
+// list. Callers must hold the store write lock.
+func Forget(store *dao.DataStore, playerID string) {
+	for _, f := range store.Friends[playerID] {
+		store.Friends[f.PlayerID] = slices.DeleteFunc(store.Friends[f.PlayerID], func(g dao.Friend) bool { return g.PlayerID == playerID })
+	}
+	delete(store.Friends, playerID)
+	delete(store.FriendRequests, playerID)
+	delete(store.Blocks, playerID)
+	for to := range store.FriendRequests {
+		dropRequest(store, to, playerID)
+	}
+	for blocker, blocked := range store.Blocks {
+		store.Blocks[blocker] = slices.DeleteFunc(blocked, func(id string) bool { return id == playerID })
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrPlayerNotFound), errors.Is(err, ErrNoRequest), errors.Is(err, ErrNotFriends), errors.Is(err, ErrNotBlocked):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, ErrBlocked):
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+	case errors.Is(err, ErrNoTarget), errors.Is(err, ErrSelf):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	default:
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
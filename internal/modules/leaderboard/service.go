@@ -0,0 +1,310 @@
+package leaderboard
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"goworld-skeleton/internal/auth"
+	"goworld-skeleton/internal/config"
+	"goworld-skeleton/internal/dao"
+	"goworld-skeleton/internal/gameday"
+	cache "goworld-skeleton/internal/redis"
+)
+
+// Boards. Level is global only; rating and wins exist globally and per game
+// mode.
+const (
+	BoardLevel  = "level"
+	BoardRating = "rating"
+	BoardWins   = "wins"
+)
+
+const (
+	keyPrefix     = "leaderboard:"
+	globalScope   = "global"
+	defaultRating = 1000
+	ratingK       = 32
+
+	defaultTop    = 20
+	maxTop        = 100
+	defaultRadius = 5
+	maxRadius     = 25
+)
+
+var (
+	ErrUnknownBoard = errors.New("unknown leaderboard")
+	ErrNotRanked    = errors.New("player is not ranked")
+)
+
+// Service keeps leaderboards as sorted sets in the cache under
+// "leaderboard:<board>:<mode>", with "global" for the all-mode board.
+type Service struct {
+	store        *dao.DataStore
+	cache        *cache.Cache
+	calendar     gameday.Calendar
+	snapshotSize int
+	// ratingMu serialises rating updates, which read several scores before
+	// writing them back.
+	ratingMu *sync.Mutex
+	logger   *log.Logger
+}
+
+// NewService constructs a leaderboard service and seeds the level board from
+// the existing characters.
+func NewService(store *dao.DataStore, cache *cache.Cache, cfg config.Config, logger *log.Logger) Service {
+	calendar, err := gameday.Load(cfg.DailyReset.Timezone, cfg.DailyReset.Hour)
+	if err != nil {
+		logger.Printf("leaderboard snapshots: %v", err)
+	}
+
+	s := Service{store: store, cache: cache, calendar: calendar, snapshotSize: cfg.LeaderboardSnapshotSize, ratingMu: &sync.Mutex{}, logger: logger}
+	store.WithRead(func(store *dao.DataStore) {
+		for _, p := range store.Players {
+			if !p.Deleted() {
+				s.SetLevel(p.ID, p.Level)
+			}
+		}
+	})
+	return s
+}
+
+// Register binds HTTP endpoints.
+func (s Service) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/api/leaderboard/", s.board)
+	mux.HandleFunc("/api/leaderboard/snapshots", s.snapshots)
+	mux.HandleFunc("/api/admin/leaderboard/snapshot", s.adminSnapshot)
+}
+
+func key(board, mode string) string {
+	if board == BoardLevel || mode == "" {
+		mode = globalScope
+	}
+	return keyPrefix + board + ":" + mode
+}
+
+// SetLevel records the player's level on the level board.
+func (s Service) SetLevel(playerID string, level int) {
+	s.cache.ZAdd(key(BoardLevel, ""), playerID, float64(level))
+}
+
+// Remove takes the player off every board.
+func (s Service) Remove(playerID string) {
+	for _, k := range s.cache.Keys(keyPrefix) {
+		s.cache.ZRem(k, playerID)
+	}
+}
+
+// Standing is the player's place on one live board. Rank counts stale
+// members not yet dropped, so it may be a little low.
+type Standing struct {
+	Board string  `json:"board"`
+	Mode  string  `json:"mode"`
+	Rank  int     `json:"rank"`
+	Score float64 `json:"score"`
+}
+
+// Standings lists every board the player is on.
+func (s Service) Standings(playerID string) []Standing {
+	standings := make([]Standing, 0)
+	for _, k := range s.cache.Keys(keyPrefix) {
+		score, ok := s.cache.ZScore(k, playerID)
+		rank, ranked := s.cache.ZRevRank(k, playerID)
+		if !ok || !ranked {
+			continue
+		}
+		board, mode, _ := strings.Cut(strings.TrimPrefix(k, keyPrefix), ":")
+		if mode == globalScope {
+			mode = ""
+		}
+		standings = append(standings, Standing{Board: board, Mode: mode, Rank: rank + 1, Score: score})
+	}
+	return standings
+}
+
+// RecordMatch updates the wins and rating boards for mode and globally.
+// Ratings use Elo against the average rating of the players with the other
+// outcome; a match without both winners and losers leaves ratings alone.
+func (s Service) RecordMatch(mode string, players, winners []string) {
+	won := make(map[string]bool, len(winners))
+	for _, id := range winners {
+		won[id] = true
+	}
+
+	for _, scope := range []string{"", mode} {
+		wins := key(BoardWins, scope)
+		for _, id := range players {
+			if won[id] {
+				s.cache.ZIncrBy(wins, id, 1)
+			} else {
+				s.cache.ZIncrBy(wins, id, 0)
+			}
+		}
+		s.updateRatings(key(BoardRating, scope), players, won)
+		if mode == "" {
+			break
+		}
+	}
+}
+
+func (s Service) updateRatings(k string, players []string, won map[string]bool) {
+	s.ratingMu.Lock()
+	defer s.ratingMu.Unlock()
+
+	ratings := make(map[string]float64, len(players))
+	for _, id := range players {
+		rating, ok := s.cache.ZScore(k, id)
+		if !ok {
+			rating = defaultRating
+		}
+		ratings[id] = rating
+	}
+
+	updated := make(map[string]float64, len(players))
+	for _, id := range players {
+		var sum float64
+		opponents := 0
+		for _, other := range players {
+			if won[other] != won[id] {
+				sum += ratings[other]
+				opponents++
+			}
+		}
+		if opponents == 0 {
+			updated[id] = ratings[id]
+			continue
+		}
+		expected := 1 / (1 + math.Pow(10, (sum/float64(opponents)-ratings[id])/400))
+		actual := 0.0
+		if won[id] {
+			actual = 1
+		}
+		updated[id] = math.Round(ratings[id] + ratingK*(actual-expected))
+	}
+	for id, rating := range updated {
+		s.cache.ZAdd(k, id, rating)
+	}
+}
+
+// Top returns the first n entries of a board.
+func (s Service) Top(board, mode string, n int) []dao.RankEntry {
+	return s.entries(key(board, mode), 0, n-1)
+}
+
+// Rank returns the player's own entry.
+func (s Service) Rank(board, mode, playerID string) (dao.RankEntry, error) {
+	k := key(board, mode)
+	for {
+		rank, ok := s.cache.ZRevRank(k, playerID)
+		if !ok {
+			return dao.RankEntry{}, ErrNotRanked
+		}
+		// A mismatch means stale members were dropped; look again.
+		if entries := s.entries(k, rank, rank); len(entries) == 1 && entries[0].PlayerID == playerID {
+			return entries[0], nil
+		}
+	}
+}
+
+// Around returns up to radius entries on either side of the player.
+func (s Service) Around(board, mode, playerID string, radius int) ([]dao.RankEntry, error) {
+	k := key(board, mode)
+	rank, ok := s.cache.ZRevRank(k, playerID)
+	if !ok {
+		return nil, ErrNotRanked
+	}
+	return s.entries(k, max(rank-radius, 0), rank+radius), nil
+}
+
+// entries reads ranks start..stop and attaches names and levels. Members
+// whose character no longer exists are dropped from the board first, so
+// ranks stay contiguous.
+func (s Service) entries(k string, start, stop int) []dao.RankEntry {
+	for {
+		members := s.cache.ZRevRange(k, start, stop)
+		entries := make([]dao.RankEntry, 0, len(members))
+		var stale []string
+		s.store.WithRead(func(store *dao.DataStore) {
+			for i, m := range members {
+				p, ok := store.Players[m.Member]
+				if !ok || p.Deleted() {
+					stale = append(stale, m.Member)
+					continue
+				}
+				entries = append(entries, dao.RankEntry{Rank: start + i + 1, PlayerID: p.ID, Name: p.Name, Level: p.Level, Score: m.Score})
+			}
+		})
+		if len(stale) == 0 {
+			return entries
+		}
+		for _, id := range stale {
+			s.cache.ZRem(k, id)
+		}
+	}
+}
+
+// board serves GET /api/leaderboard/{board}[/me|/around]?mode=&limit=&radius=.
+func (s Service) board(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+
+	board, view, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/leaderboard/"), "/")
+	if board != BoardLevel && board != BoardRating && board != BoardWins {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": ErrUnknownBoard.Error()})
+		return
+	}
+	mode := r.URL.Query().Get("mode")
+	if board == BoardLevel {
+		mode = ""
+	}
+
+	switch view {
+	case "":
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		if limit <= 0 || limit > maxTop {
+			limit = defaultTop
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"board": board, "mode": mode, "entries": s.Top(board, mode, limit), "total": s.cache.ZCard(key(board, mode))})
+	case "me", "around":
+		playerID, err := auth.ResolvePlayer(r, "")
+		if err != nil {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+			return
+		}
+		if view == "me" {
+			entry, err := s.Rank(board, mode, playerID)
+			if err != nil {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, entry)
+			return
+		}
+
+		radius, _ := strconv.Atoi(r.URL.Query().Get("radius"))
+		if radius <= 0 || radius > maxRadius {
+			radius = defaultRadius
+		}
+		entries, err := s.Around(board, mode, playerID, radius)
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"board": board, "mode": mode, "entries": entries})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
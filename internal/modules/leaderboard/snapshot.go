@@ -0,0 +1,85 @@
+package leaderboard
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"goworld-skeleton/internal/auth"
+	"goworld-skeleton/internal/dao"
+)
+
+// RunSnapshots snapshots every board at the end of each game week. It
+// blocks, so run it in its own goroutine.
+func (s Service) RunSnapshots() {
+	for {
+		end := s.calendar.NextWeeklyReset(time.Now())
+		time.Sleep(time.Until(end))
+		s.Snapshot(s.calendar.Week(end.Add(-time.Second)))
+	}
+}
+
+// Snapshot stores the top entries of every board under period.
+func (s Service) Snapshot(period string) []dao.LeaderboardSnapshot {
+	now := time.Now()
+	taken := make([]dao.LeaderboardSnapshot, 0)
+	for _, k := range s.cache.Keys(keyPrefix) {
+		board, mode, _ := strings.Cut(strings.TrimPrefix(k, keyPrefix), ":")
+		if mode == globalScope {
+			mode = ""
+		}
+		taken = append(taken, dao.LeaderboardSnapshot{Board: board, Mode: mode, Period: period, TakenAt: now, Entries: s.entries(k, 0, s.snapshotSize-1)})
+	}
+
+	s.store.WithLock(func(store *dao.DataStore) {
+		store.LeaderboardSnapshots = append(store.LeaderboardSnapshots, taken...)
+	})
+	s.logger.Printf("leaderboard snapshot %s: %d boards", period, len(taken))
+	return taken
+}
+
+// snapshots lists stored snapshots filtered by ?board=&mode=&period=.
+func (s Service) snapshots(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+
+	q := r.URL.Query()
+	found := make([]dao.LeaderboardSnapshot, 0)
+	s.store.WithRead(func(store *dao.DataStore) {
+		for _, snap := range store.LeaderboardSnapshots {
+			if (q.Get("board") == "" || snap.Board == q.Get("board")) &&
+				snap.Mode == q.Get("mode") &&
+				(q.Get("period") == "" || snap.Period == q.Get("period")) {
+				found = append(found, snap)
+			}
+		}
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{"snapshots": found})
+}
+
+// adminSnapshot takes a snapshot now, labelled with the optional "period"
+// or else the current game week.
+func (s Service) adminSnapshot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	var input struct {
+		Period string `json:"period"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && err != io.EOF {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if input.Period == "" {
+		input.Period = s.calendar.Week(time.Now())
+	}
+
+	s.logger.Printf("leaderboard snapshot requested by %s", auth.Operator(r))
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"snapshots": s.Snapshot(input.Period)})
+}
@@ -2,27 +2,39 @@ package mail
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
+	"goworld-skeleton/internal/auth"
 	"goworld-skeleton/internal/dao"
+	"goworld-skeleton/internal/modules/bag"
+)
+
+var (
+	ErrMailNotFound   = errors.New("mail not found")
+	ErrAlreadyClaimed = errors.New("attachments already claimed")
+	ErrNoAttachments  = errors.New("mail has no attachments")
 )
 
 // Service exposes mail endpoints.
 type Service struct {
 	store  *dao.DataStore
+	bag    bag.Service
 	logger *log.Logger
 }
 
 // NewService constructs a mail service.
-func NewService(store *dao.DataStore, logger *log.Logger) Service {
-	return Service{store: store, logger: logger}
+func NewService(store *dao.DataStore, bags bag.Service, logger *log.Logger) Service {
+	return Service{store: store, bag: bags, logger: logger}
 }
 
 // Register binds HTTP endpoints.
 func (s Service) Register(mux *http.ServeMux) {
 	mux.HandleFunc("/api/mail/", s.list)
+	mux.HandleFunc("/api/mail/claim", s.claim)
 }
 
 func (s Service) list(w http.ResponseWriter, r *http.Request) {
@@ -31,7 +43,11 @@ func (s Service) list(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	playerID := strings.TrimPrefix(r.URL.Path, "/api/mail/")
+	playerID, err := auth.ResolvePlayer(r, strings.TrimPrefix(r.URL.Path, "/api/mail/"))
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
 	var mails []dao.Mail
 	s.store.WithRead(func(store *dao.DataStore) {
 		mails = store.Mails[playerID]
@@ -46,6 +62,67 @@ func (s Service) list(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]interface{}{"messages": mails})
 }
 
+type claimInput struct {
+	MailID string `json:"mail_id"`
+}
+
+// claim moves a mail's attachments into the caller's bag. It fails without
+// changes when they do not all fit.
+func (s Service) claim(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	playerID, err := auth.ResolvePlayer(r, "")
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var input claimInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var result bag.Result
+	s.store.WithLock(func(store *dao.DataStore) {
+		now := time.Now()
+		mails := append([]dao.Mail(nil), store.Mails[playerID]...)
+		for i := range mails {
+			if mails[i].ID != input.MailID {
+				continue
+			}
+			switch {
+			case !mails[i].ClaimedAt.IsZero():
+				err = ErrAlreadyClaimed
+			case len(mails[i].Attachments) == 0:
+				err = ErrNoAttachments
+			default:
+				result, err = s.bag.GiveLocked(store, playerID, bag.Grant{Items: mails[i].Attachments, Overflow: bag.OverflowReject}, now)
+				if err == nil {
+					mails[i].ClaimedAt = now
+					store.Mails[playerID] = mails
+				}
+			}
+			return
+		}
+		err = ErrMailNotFound
+	})
+	switch {
+	case errors.Is(err, ErrMailNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	case err != nil:
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.logger.Printf("mail %s claimed by %s", input.MailID, playerID)
+	writeJSON(w, http.StatusOK, result)
+}
+
 func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -2,27 +2,41 @@ package match
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"slices"
 	"time"
 
+	"goworld-skeleton/internal/auth"
 	"goworld-skeleton/internal/dao"
+	"goworld-skeleton/internal/event"
+	"goworld-skeleton/internal/modules/leaderboard"
+)
+
+var (
+	ErrMatchNotFound = errors.New("match not found")
+	ErrMatchFinished = errors.New("match already finished")
+	ErrNotSeated     = errors.New("winner is not seated in the match")
 )
 
 // Service exposes matchmaking endpoints.
 type Service struct {
 	store  *dao.DataStore
+	events *event.Bus
+	boards leaderboard.Service
 	logger *log.Logger
 }
 
 // NewService constructs a match service.
-func NewService(store *dao.DataStore, logger *log.Logger) Service {
-	return Service{store: store, logger: logger}
+func NewService(store *dao.DataStore, events *event.Bus, boards leaderboard.Service, logger *log.Logger) Service {
+	return Service{store: store, events: events, boards: boards, logger: logger}
 }
 
 // Register binds HTTP endpoints.
 func (s Service) Register(mux *http.ServeMux) {
 	mux.HandleFunc("/api/match/enqueue", s.enqueue)
+	mux.HandleFunc("/api/admin/match/result", s.reportResult)
 }
 
 type enqueueInput struct {
@@ -42,14 +56,79 @@ func (s Service) enqueue(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	playerID, err := auth.ResolvePlayer(r, input.PlayerID)
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+
 	matchID := generateMatchID()
-	room := dao.Room{ID: matchID, Game: input.Mode, Players: []string{input.PlayerID}, MaxPlayers: 4, Status: "matching"}
+	room := dao.Room{ID: matchID, Game: input.Mode, Players: []string{playerID}, MaxPlayers: 4, Status: "matching"}
 	s.store.WithLock(func(store *dao.DataStore) { store.Rooms[room.ID] = room })
 
-	s.logger.Printf("player %s enqueued for %s", input.PlayerID, input.Mode)
+	s.logger.Printf("player %s enqueued for %s", playerID, input.Mode)
 	writeJSON(w, http.StatusAccepted, map[string]interface{}{"match_id": matchID, "room": room})
 }
 
+type resultInput struct {
+	MatchID string   `json:"match_id"`
+	Winners []string `json:"winners"`
+}
+
+// reportResult lets the game server close a match. It updates the wins and
+// rating leaderboards, and every seated player gets a match.finished event;
+// those listed in winners are marked as having won.
+func (s Service) reportResult(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	var input resultInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var room dao.Room
+	var err error
+	s.store.WithLock(func(store *dao.DataStore) {
+		var ok bool
+		room, ok = store.Rooms[input.MatchID]
+		switch {
+		case !ok:
+			err = ErrMatchNotFound
+			return
+		case room.Status == "finished":
+			err = ErrMatchFinished
+			return
+		}
+		for _, winner := range input.Winners {
+			if !slices.Contains(room.Players, winner) {
+				err = ErrNotSeated
+				return
+			}
+		}
+		room.Status = "finished"
+		store.Rooms[room.ID] = room
+	})
+	switch {
+	case errors.Is(err, ErrMatchNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	case err != nil:
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.logger.Printf("match %s (%s) finished by %s, winners %v", room.ID, room.Game, auth.Operator(r), input.Winners)
+	s.boards.RecordMatch(room.Game, room.Players, input.Winners)
+	for _, playerID := range room.Players {
+		s.events.Publish(event.MatchFinished, playerID, event.MatchPayload{MatchID: room.ID, Mode: room.Game, Won: slices.Contains(input.Winners, playerID)})
+	}
+	writeJSON(w, http.StatusOK, room)
+}
+
 func generateMatchID() string {
 	return "match-" + time.Now().Format("150405.000")
 }
@@ -0,0 +1,115 @@
+package player
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"goworld-skeleton/internal/auth"
+	"goworld-skeleton/internal/dao"
+)
+
+const (
+	statsKeyPrefix = "stats:"
+	// statsMaxAge bounds how long a computed sheet is reused even when
+	// nothing invalidates it.
+	statsMaxAge = 5 * time.Minute
+)
+
+// StatSheet breaks a player's attributes down by source.
+type StatSheet struct {
+	PlayerID   string    `json:"player_id"`
+	Level      int       `json:"level"`
+	Base       dao.Stats `json:"base"`
+	Equipment  dao.Stats `json:"equipment"`
+	Buffs      dao.Stats `json:"buffs"`
+	Total      dao.Stats `json:"total"`
+	ComputedAt time.Time `json:"computed_at"`
+}
+
+// Stats returns the player's attributes: base values for their level, plus
+// equipped item bonuses, plus active buffs. Sheets are cached until the
+// player levels up, changes equipment or a buff expires.
+//
+// The sheet is cached while the store lock is still held. Changes that
+// invalidate it only do so after releasing the write lock, so a sheet
+// computed from older data is always dropped again.
+func (s Service) Stats(playerID string) (StatSheet, error) {
+	if value, ok := s.cache.Get(statsKeyPrefix + playerID); ok {
+		if sheet, ok := value.(StatSheet); ok {
+			return sheet, nil
+		}
+	}
+
+	var sheet StatSheet
+	var err error
+	s.store.WithRead(func(store *dao.DataStore) {
+		p, ok := store.Players[playerID]
+		if !ok || p.Deleted() {
+			err = ErrPlayerNotFound
+			return
+		}
+		var validUntil time.Time
+		sheet, validUntil = computeStats(store, p, time.Now())
+		s.cache.Set(statsKeyPrefix+playerID, sheet, time.Until(validUntil))
+	})
+	if err != nil {
+		return StatSheet{}, err
+	}
+	return sheet, nil
+}
+
+// InvalidateStats drops the cached sheet so the next read recomputes it.
+func (s Service) InvalidateStats(playerID string) {
+	s.cache.Delete(statsKeyPrefix + playerID)
+}
+
+// computeStats derives the sheet and reports until when it stays valid.
+// Callers must hold the store lock.
+func computeStats(store *dao.DataStore, p dao.Player, now time.Time) (StatSheet, time.Time) {
+	sheet := StatSheet{PlayerID: p.ID, Level: p.Level, ComputedAt: now}
+	for _, def := range store.Levels {
+		if def.Level > p.Level {
+			break
+		}
+		sheet.Base = def.Stats
+	}
+
+	for _, itemID := range p.Equipment {
+		for _, item := range store.Items {
+			if item.ID == itemID {
+				sheet.Equipment = sheet.Equipment.Add(item.Stats)
+				break
+			}
+		}
+	}
+
+	validUntil := now.Add(statsMaxAge)
+	for _, buff := range store.Buffs[p.ID] {
+		if !now.Before(buff.ExpiresAt) {
+			continue
+		}
+		sheet.Buffs = sheet.Buffs.Add(buff.Stats)
+		if buff.ExpiresAt.Before(validUntil) {
+			validUntil = buff.ExpiresAt
+		}
+	}
+
+	sheet.Total = sheet.Base.Add(sheet.Equipment).Add(sheet.Buffs)
+	return sheet, validUntil
+}
+
+func (s Service) getStats(w http.ResponseWriter, r *http.Request, requested string) {
+	playerID, err := auth.ResolvePlayer(r, requested)
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+
+	sheet, err := s.Stats(playerID)
+	if errors.Is(err, ErrPlayerNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, sheet)
+}
@@ -0,0 +1,314 @@
+package player
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"goworld-skeleton/internal/auth"
+	"goworld-skeleton/internal/dao"
+	"goworld-skeleton/internal/event"
+	"goworld-skeleton/internal/modules/friend"
+	"goworld-skeleton/internal/modules/trade"
+)
+
+var (
+	errNoFreeSlot        = errors.New("no free character slot")
+	errCharacterNotFound = errors.New("character not found")
+	errCharacterDeleted  = errors.New("character is pending deletion")
+	errCharacterLive     = errors.New("character is not pending deletion")
+)
+
+// NewCharacter builds a level 1 character for the account with a fresh ID.
+func NewCharacter(accountID, name string) (dao.Player, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return dao.Player{}, err
+	}
+	now := time.Now()
+	return dao.Player{
+		ID:        "c-" + hex.EncodeToString(buf),
+		AccountID: accountID,
+		Name:      name,
+		Level:     1,
+		LastLogin: now,
+		CreatedAt: now,
+	}, nil
+}
+
+// Characters returns the account's characters, including those pending
+// deletion, oldest first. Callers must hold the store lock.
+func Characters(store *dao.DataStore, accountID string) []dao.Player {
+	characters := make([]dao.Player, 0)
+	for _, p := range store.Players {
+		if p.AccountID == accountID {
+			characters = append(characters, p)
+		}
+	}
+	sort.Slice(characters, func(i, j int) bool { return characters[i].CreatedAt.Before(characters[j].CreatedAt) })
+	return characters
+}
+
+// LatestCharacter returns the most recently played live character of the
+// account, or "" if it has none. Callers must hold the store lock.
+func LatestCharacter(store *dao.DataStore, accountID string) string {
+	var latest dao.Player
+	for _, p := range Characters(store, accountID) {
+		if !p.Deleted() && (latest.ID == "" || p.LastLogin.After(latest.LastLogin)) {
+			latest = p
+		}
+	}
+	return latest.ID
+}
+
+// purgeExpired removes the account's characters whose deletion grace period
+// has ended, along with their bag and mail. Callers must hold the store write
+// lock.
+func purgeExpired(store *dao.DataStore, accountID string, now time.Time) {
+	for id, p := range store.Players {
+		if p.AccountID == accountID && purgeDue(p, now) {
+			purgeCharacter(store, id)
+		}
+	}
+}
+
+func purgeDue(p dao.Player, now time.Time) bool {
+	return p.Deleted() && !now.Before(p.PurgeAt)
+}
+
+// purgeCharacter removes the character and everything it owns. Callers must
+// hold the store write lock.
+func purgeCharacter(store *dao.DataStore, id string) {
+	delete(store.Players, id)
+	delete(store.Bags, id)
+	delete(store.BagRevisions, id)
+	delete(store.Mails, id)
+	delete(store.Buffs, id)
+	delete(store.QuestProgress, id)
+	trade.Abandon(store, id)
+	friend.Forget(store, id)
+}
+
+// SweepCharacters purges characters of every account whose deletion grace
+// period has ended, checking every interval, so accounts that never come
+// back do not keep them forever. It blocks, so run it in its own goroutine.
+func (s Service) SweepCharacters(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		s.purgeDueCharacters(now)
+	}
+}
+
+func (s Service) purgeDueCharacters(now time.Time) {
+	var purged []string
+	s.store.WithLock(func(store *dao.DataStore) {
+		for id, p := range store.Players {
+			if purgeDue(p, now) {
+				purgeCharacter(store, id)
+				purged = append(purged, id)
+			}
+		}
+	})
+
+	for _, id := range purged {
+		s.presence.Forget(id)
+		s.logger.Printf("character %s purged", id)
+	}
+}
+
+func (s Service) characters(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.listCharacters(w, r)
+	case http.MethodPost:
+		s.createCharacter(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s Service) listCharacters(w http.ResponseWriter, r *http.Request) {
+	accountID := auth.AccountID(r)
+	var characters []dao.Player
+	s.store.WithLock(func(store *dao.DataStore) {
+		purgeExpired(store, accountID, time.Now())
+		characters = Characters(store, accountID)
+	})
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"characters": characters,
+		"slots":      s.characterCfg.Slots,
+		"selected":   auth.PlayerID(r),
+	})
+}
+
+type createCharacterInput struct {
+	Name string `json:"name"`
+}
+
+func (s Service) createCharacter(w http.ResponseWriter, r *http.Request) {
+	var input createCharacterInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	name, err := s.validateName(input.Name)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	accountID := auth.AccountID(r)
+	character, err := NewCharacter(accountID, name)
+	if err != nil {
+		s.logger.Printf("create character for %s: %v", accountID, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "could not create character"})
+		return
+	}
+
+	s.store.WithLock(func(store *dao.DataStore) {
+		purgeExpired(store, accountID, time.Now())
+		if len(Characters(store, accountID)) >= s.characterCfg.Slots {
+			err = errNoFreeSlot
+			return
+		}
+		if nameTaken(store, name, "") {
+			err = errNameTaken
+			return
+		}
+		store.Players[character.ID] = character
+	})
+	if err != nil {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.logger.Printf("account %s created character %s", accountID, character.ID)
+	writeJSON(w, http.StatusCreated, character)
+}
+
+type characterInput struct {
+	CharacterID string `json:"character_id"`
+}
+
+// deleteCharacter marks a character for deletion. It keeps its slot and can
+// be restored until the grace period ends.
+func (s Service) deleteCharacter(w http.ResponseWriter, r *http.Request) {
+	s.updateCharacter(w, r, func(p *dao.Player, now time.Time) error {
+		if p.Deleted() {
+			return errCharacterDeleted
+		}
+		p.DeletedAt = now
+		p.PurgeAt = now.Add(s.characterCfg.DeleteGrace)
+		return nil
+	})
+}
+
+func (s Service) restoreCharacter(w http.ResponseWriter, r *http.Request) {
+	s.updateCharacter(w, r, func(p *dao.Player, _ time.Time) error {
+		if !p.Deleted() {
+			return errCharacterLive
+		}
+		p.DeletedAt = time.Time{}
+		p.PurgeAt = time.Time{}
+		return nil
+	})
+}
+
+func (s Service) updateCharacter(w http.ResponseWriter, r *http.Request, apply func(*dao.Player, time.Time) error) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	var input characterInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	accountID := auth.AccountID(r)
+	var character dao.Player
+	var err error
+	s.store.WithLock(func(store *dao.DataStore) {
+		now := time.Now()
+		purgeExpired(store, accountID, now)
+		p, ok := store.Players[input.CharacterID]
+		if !ok || p.AccountID != accountID {
+			err = errCharacterNotFound
+			return
+		}
+		if err = apply(&p, now); err != nil {
+			return
+		}
+		store.Players[p.ID] = p
+		character = p
+	})
+
+	switch {
+	case errors.Is(err, errCharacterNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	case err != nil:
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	}
+
+	if character.Deleted() {
+		s.sessions.Deselect(accountID, character.ID)
+		s.boards.Remove(character.ID)
+		s.logger.Printf("account %s deleted character %s, purge at %s", accountID, character.ID, character.PurgeAt.Format(time.RFC3339))
+	} else {
+		s.boards.SetLevel(character.ID, character.Level)
+		s.logger.Printf("account %s restored character %s", accountID, character.ID)
+	}
+	writeJSON(w, http.StatusOK, character)
+}
+
+// selectCharacter switches the caller's session to one of its characters.
+func (s Service) selectCharacter(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	var input characterInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	session, _ := auth.SessionFrom(r.Context())
+	var character dao.Player
+	s.store.WithLock(func(store *dao.DataStore) {
+		purgeExpired(store, session.AccountID, time.Now())
+		character = store.Players[input.CharacterID]
+	})
+
+	switch {
+	case character.ID == "" || character.AccountID != session.AccountID:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": errCharacterNotFound.Error()})
+		return
+	case character.Deleted():
+		writeJSON(w, http.StatusConflict, map[string]string{"error": errCharacterDeleted.Error()})
+		return
+	}
+
+	if _, err := s.sessions.Select(session, character.ID); err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.logger.Printf("account %s selected character %s", session.AccountID, character.ID)
+	if session.CharacterID != "" && session.CharacterID != character.ID {
+		s.events.Publish(event.PlayerLogout, session.CharacterID, nil)
+	}
+	s.events.Publish(event.PlayerLogin, character.ID, event.PlayerLoginPayload{AccountID: session.AccountID})
+	writeJSON(w, http.StatusOK, character)
+}
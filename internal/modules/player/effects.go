@@ -0,0 +1,81 @@
+package player
+
+import (
+	"fmt"
+	"time"
+
+	"goworld-skeleton/internal/dao"
+	"goworld-skeleton/internal/modules/bag"
+)
+
+// heal restores the item's amount of HP per item used.
+func (s Service) heal(u *bag.Use) (interface{}, error) {
+	p, ok := u.Store.Players[u.PlayerID]
+	if !ok || p.Deleted() {
+		return nil, ErrPlayerNotFound
+	}
+	if p.Damage <= 0 {
+		return nil, fmt.Errorf("%w: already at full health", bag.ErrNoEffect)
+	}
+
+	healed := int(min(int64(p.Damage), u.Item.Use.Amount*int64(u.Quantity)))
+	p.Damage -= healed
+	u.Store.Players[p.ID] = p
+
+	sheet, _ := computeStats(u.Store, p, u.Now)
+	return map[string]interface{}{"healed": healed, "health": Health{PlayerID: p.ID, HP: sheet.Total.HP - p.Damage, MaxHP: sheet.Total.HP}}, nil
+}
+
+// grantExpEffect grants the item's amount of experience per item used.
+func (s Service) grantExpEffect(u *bag.Use) (interface{}, error) {
+	p, ok := u.Store.Players[u.PlayerID]
+	if !ok || p.Deleted() {
+		return nil, ErrPlayerNotFound
+	}
+	if n := len(u.Store.Levels); n > 0 && p.Experience >= u.Store.Levels[n-1].Experience {
+		return nil, fmt.Errorf("%w: already at max level", bag.ErrNoEffect)
+	}
+
+	progress, err := s.grantExperienceLocked(u.Store, u.PlayerID, int(u.Item.Use.Amount)*u.Quantity, u.Now)
+	if err != nil {
+		return nil, err
+	}
+	u.After(func() { s.announceLevels(progress, "use "+u.Item.ID) })
+	return progress, nil
+}
+
+// applyBuff adds the item's stat bonus for its duration per item used.
+// Using more while the buff is active extends it instead of stacking.
+func (s Service) applyBuff(u *bag.Use) (interface{}, error) {
+	if p, ok := u.Store.Players[u.PlayerID]; !ok || p.Deleted() {
+		return nil, ErrPlayerNotFound
+	}
+
+	source := "item:" + u.Item.ID
+	duration := time.Duration(u.Item.Use.Seconds) * time.Second * time.Duration(u.Quantity)
+	buffs := make([]dao.Buff, 0, len(u.Store.Buffs[u.PlayerID])+1)
+	var applied dao.Buff
+	for _, buff := range u.Store.Buffs[u.PlayerID] {
+		if !u.Now.Before(buff.ExpiresAt) {
+			continue
+		}
+		if buff.Source == source {
+			buff.ExpiresAt = buff.ExpiresAt.Add(duration)
+			applied = buff
+		}
+		buffs = append(buffs, buff)
+	}
+	if applied.ID == "" {
+		applied = dao.Buff{
+			ID:        fmt.Sprintf("buff-%s-%d", u.PlayerID, u.Now.UnixNano()),
+			Source:    source,
+			Stats:     u.Item.Use.Stats,
+			ExpiresAt: u.Now.Add(duration),
+		}
+		buffs = append(buffs, applied)
+	}
+	u.Store.Buffs[u.PlayerID] = buffs
+
+	u.After(func() { s.InvalidateStats(u.PlayerID) })
+	return applied, nil
+}
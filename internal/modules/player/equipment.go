@@ -0,0 +1,192 @@
+package player
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"goworld-skeleton/internal/auth"
+	"goworld-skeleton/internal/dao"
+	"goworld-skeleton/internal/event"
+	"goworld-skeleton/internal/modules/bag"
+)
+
+// Slots lists the equipment slots every player has.
+var Slots = []string{dao.SlotWeapon, dao.SlotArmor, dao.SlotAccessory}
+
+var (
+	ErrNotEquippable = errors.New("item cannot be equipped")
+	ErrLevelTooLow   = errors.New("level too low for this item")
+	ErrUnknownSlot   = errors.New("unknown equipment slot")
+	ErrSlotEmpty     = errors.New("nothing is equipped in that slot")
+)
+
+// Equip moves one of the item from the player's bag into its slot. Whatever
+// the slot held goes back to the bag; if it does not fit nothing changes.
+func (s Service) Equip(playerID, itemID string) (map[string]string, error) {
+	var equipment map[string]string
+	var change event.EquipmentPayload
+	var err error
+	s.store.WithLock(func(store *dao.DataStore) {
+		p, ok := store.Players[playerID]
+		if !ok || p.Deleted() {
+			err = ErrPlayerNotFound
+			return
+		}
+		item, ok := bag.FindItem(store, itemID)
+		if !ok {
+			err = bag.ErrUnknownItem
+			return
+		}
+		if !slices.Contains(Slots, item.Slot) {
+			err = ErrNotEquippable
+			return
+		}
+		if p.Level < item.Level {
+			err = fmt.Errorf("%w: requires level %d", ErrLevelTooLow, item.Level)
+			return
+		}
+
+		before := store.Bags[playerID]
+		if err = bag.RemoveLocked(store, playerID, itemID, 1); err != nil {
+			return
+		}
+		previous := p.Equipment[item.Slot]
+		if previous != "" {
+			grant := bag.Grant{Items: []dao.MailAttachment{{ItemID: previous, Quantity: 1}}}
+			if _, err = s.bag.GiveLocked(store, playerID, grant, time.Now()); err != nil {
+				store.Bags[playerID] = before
+				return
+			}
+		}
+
+		p.Equipment = withSlot(p.Equipment, item.Slot, itemID)
+		store.Players[p.ID] = p
+		equipment = p.Equipment
+		change = event.EquipmentPayload{Slot: item.Slot, ItemID: itemID, Previous: previous}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(event.EquipmentChanged, playerID, change)
+	s.logger.Printf("player %s equipped %s in %s", playerID, itemID, change.Slot)
+	return equipment, nil
+}
+
+// Unequip moves the item in slot back to the player's bag, which must have
+// room for it.
+func (s Service) Unequip(playerID, slot string) (map[string]string, error) {
+	if !slices.Contains(Slots, slot) {
+		return nil, ErrUnknownSlot
+	}
+
+	var equipment map[string]string
+	var previous string
+	var err error
+	s.store.WithLock(func(store *dao.DataStore) {
+		p, ok := store.Players[playerID]
+		if !ok || p.Deleted() {
+			err = ErrPlayerNotFound
+			return
+		}
+		previous = p.Equipment[slot]
+		if previous == "" {
+			err = ErrSlotEmpty
+			return
+		}
+
+		grant := bag.Grant{Items: []dao.MailAttachment{{ItemID: previous, Quantity: 1}}}
+		if _, err = s.bag.GiveLocked(store, playerID, grant, time.Now()); err != nil {
+			return
+		}
+		p.Equipment = withSlot(p.Equipment, slot, "")
+		store.Players[p.ID] = p
+		equipment = p.Equipment
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(event.EquipmentChanged, playerID, event.EquipmentPayload{Slot: slot, Previous: previous})
+	s.logger.Printf("player %s unequipped %s from %s", playerID, previous, slot)
+	return equipment, nil
+}
+
+// withSlot returns a copy of equipment with slot set to itemID, or cleared
+// when itemID is empty.
+func withSlot(equipment map[string]string, slot, itemID string) map[string]string {
+	next := make(map[string]string, len(equipment)+1)
+	for k, v := range equipment {
+		next[k] = v
+	}
+	if itemID == "" {
+		delete(next, slot)
+	} else {
+		next[slot] = itemID
+	}
+	return next
+}
+
+type equipInput struct {
+	ItemID string `json:"item_id"`
+	Slot   string `json:"slot"`
+}
+
+// equip handles POST /api/player/equip with {"item_id": ...}.
+func (s Service) equip(w http.ResponseWriter, r *http.Request) {
+	s.changeEquipment(w, r, func(playerID string, input equipInput) (map[string]string, error) {
+		return s.Equip(playerID, input.ItemID)
+	})
+}
+
+// unequip handles POST /api/player/unequip with {"slot": ...}.
+func (s Service) unequip(w http.ResponseWriter, r *http.Request) {
+	s.changeEquipment(w, r, func(playerID string, input equipInput) (map[string]string, error) {
+		return s.Unequip(playerID, input.Slot)
+	})
+}
+
+// changeEquipment applies change for the selected character and responds
+// with the new equipment and stat sheet.
+func (s Service) changeEquipment(w http.ResponseWriter, r *http.Request, change func(string, equipInput) (map[string]string, error)) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	playerID, err := auth.ResolvePlayer(r, "")
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var input equipInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	equipment, err := change(playerID, input)
+	switch {
+	case errors.Is(err, ErrPlayerNotFound), errors.Is(err, bag.ErrUnknownItem):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	case errors.Is(err, ErrLevelTooLow), errors.Is(err, ErrSlotEmpty), errors.Is(err, bag.ErrNotEnough), errors.Is(err, bag.ErrBagFull):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	case err != nil:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	sheet, err := s.Stats(playerID)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"equipment": equipment, "stats": sheet})
+}
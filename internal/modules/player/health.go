@@ -0,0 +1,74 @@
+package player
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"goworld-skeleton/internal/auth"
+	"goworld-skeleton/internal/dao"
+)
+
+var errInvalidDamage = errors.New("amount must be positive")
+
+// Health is a player's current and maximum HP.
+type Health struct {
+	PlayerID string `json:"player_id"`
+	HP       int    `json:"hp"`
+	MaxHP    int    `json:"max_hp"`
+}
+
+// Damage takes amount HP from the player, never below zero. Healing items
+// restore it.
+func (s Service) Damage(playerID string, amount int) (Health, error) {
+	if amount <= 0 {
+		return Health{}, errInvalidDamage
+	}
+
+	var health Health
+	var err error
+	s.store.WithLock(func(store *dao.DataStore) {
+		p, ok := store.Players[playerID]
+		if !ok || p.Deleted() {
+			err = ErrPlayerNotFound
+			return
+		}
+		sheet, _ := computeStats(store, p, time.Now())
+		p.Damage = min(p.Damage+amount, sheet.Total.HP)
+		store.Players[p.ID] = p
+		health = Health{PlayerID: p.ID, HP: sheet.Total.HP - p.Damage, MaxHP: sheet.Total.HP}
+	})
+	return health, err
+}
+
+type damageInput struct {
+	PlayerID string `json:"player_id"`
+	Amount   int    `json:"amount"`
+	Reason   string `json:"reason"`
+}
+
+// adminDamage lets ops or the combat server apply damage to a player.
+func (s Service) adminDamage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	var input damageInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	health, err := s.Damage(input.PlayerID, input.Amount)
+	switch {
+	case errors.Is(err, ErrPlayerNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case err != nil:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	default:
+		s.logger.Printf("player %s: %s dealt %d damage (%s)", input.PlayerID, auth.Operator(r), input.Amount, input.Reason)
+		writeJSON(w, http.StatusOK, health)
+	}
+}
@@ -0,0 +1,154 @@
+package player
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"goworld-skeleton/internal/auth"
+	"goworld-skeleton/internal/dao"
+	"goworld-skeleton/internal/event"
+	"goworld-skeleton/internal/modules/bag"
+)
+
+// ErrPlayerNotFound is returned for unknown or deleted characters.
+var ErrPlayerNotFound = errors.New("player not found")
+
+// Progress reports a player's level after experience was granted.
+type Progress struct {
+	PlayerID     string `json:"player_id"`
+	Level        int    `json:"level"`
+	Experience   int    `json:"experience"`
+	LevelsGained []int  `json:"levels_gained"`
+	// Capped is set when experience was discarded at the max level.
+	Capped bool `json:"capped"`
+}
+
+// GrantExperience adds experience to a player, applies every level reached on
+// the curve in store.Levels, delivers level rewards and publishes one
+// event.LevelUp per level gained.
+func (s Service) GrantExperience(playerID string, amount int, reason string) (Progress, error) {
+	if amount <= 0 {
+		return Progress{}, errors.New("amount must be positive")
+	}
+
+	var progress Progress
+	var err error
+	s.store.WithLock(func(store *dao.DataStore) {
+		progress, err = s.grantExperienceLocked(store, playerID, amount, time.Now())
+	})
+	if err != nil {
+		return Progress{}, err
+	}
+
+	s.announceLevels(progress, reason)
+	return progress, nil
+}
+
+// grantExperienceLocked is GrantExperience without the events; pass its
+// result to announceLevels once the lock is released. Callers must hold the
+// store write lock.
+func (s Service) grantExperienceLocked(store *dao.DataStore, playerID string, amount int, now time.Time) (Progress, error) {
+	p, ok := store.Players[playerID]
+	if !ok || p.Deleted() {
+		return Progress{}, ErrPlayerNotFound
+	}
+	if len(store.Levels) == 0 {
+		return Progress{}, errors.New("level curve is empty")
+	}
+
+	progress := Progress{PlayerID: playerID, LevelsGained: make([]int, 0)}
+	maxLevel := store.Levels[len(store.Levels)-1]
+	p.Experience += amount
+	if p.Experience >= maxLevel.Experience {
+		progress.Capped = p.Experience > maxLevel.Experience
+		p.Experience = maxLevel.Experience
+	}
+
+	for _, def := range store.Levels {
+		if def.Level <= p.Level || p.Experience < def.Experience {
+			continue
+		}
+		p.Level = def.Level
+		progress.LevelsGained = append(progress.LevelsGained, def.Level)
+		s.deliverLevelRewards(store, p.ID, def, now)
+	}
+
+	store.Players[p.ID] = p
+	progress.Level = p.Level
+	progress.Experience = p.Experience
+	return progress, nil
+}
+
+// announceLevels publishes one event.LevelUp per level gained.
+func (s Service) announceLevels(progress Progress, reason string) {
+	playerID := progress.PlayerID
+	for _, level := range progress.LevelsGained {
+		s.events.Publish(event.LevelUp, playerID, event.LevelUpPayload{Level: level, Reason: reason})
+	}
+	if len(progress.LevelsGained) > 0 {
+		s.logger.Printf("player %s reached level %d (%s)", playerID, progress.Level, reason)
+	}
+}
+
+// deliverLevelRewards grants the rewards of def, into the bag with overflow
+// mailed or straight to mail depending on def.Delivery. If the bag refuses
+// them they are mailed instead, so a level never loses its rewards. Callers
+// must hold the store write lock.
+func (s Service) deliverLevelRewards(store *dao.DataStore, playerID string, def dao.LevelDef, now time.Time) {
+	if len(def.Rewards) == 0 {
+		return
+	}
+
+	mail := dao.Mail{
+		ID:          fmt.Sprintf("levelup-%s-%d", playerID, def.Level),
+		Subject:     fmt.Sprintf("升级奖励：%d 级", def.Level),
+		Body:        "恭喜升级，奖励已随邮件发放。",
+		Attachments: append([]dao.MailAttachment(nil), def.Rewards...),
+	}
+	if def.Delivery == dao.RewardToBag {
+		_, err := s.bag.GiveLocked(store, playerID, bag.Grant{Items: mail.Attachments, Overflow: bag.OverflowMail, MailID: mail.ID, Subject: mail.Subject, Body: mail.Body}, now)
+		if err == nil {
+			return
+		}
+		s.logger.Printf("level %d rewards for %s: %v, sending them by mail", def.Level, playerID, err)
+	}
+
+	store.Mails[playerID] = append(store.Mails[playerID], mail)
+}
+
+type grantExperienceInput struct {
+	PlayerID string `json:"player_id"`
+	Amount   int    `json:"amount"`
+	Reason   string `json:"reason"`
+}
+
+// adminGrantExperience lets ops grant experience, e.g. for compensation.
+func (s Service) adminGrantExperience(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	var input grantExperienceInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	reason := "gm:" + auth.Operator(r)
+	if input.Reason != "" {
+		reason += ":" + input.Reason
+	}
+	progress, err := s.GrantExperience(input.PlayerID, input.Amount, reason)
+	switch {
+	case errors.Is(err, ErrPlayerNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case err != nil:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	default:
+		writeJSON(w, http.StatusOK, progress)
+	}
+}
@@ -0,0 +1,141 @@
+package player
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"goworld-skeleton/internal/auth"
+	"goworld-skeleton/internal/dao"
+	"goworld-skeleton/internal/event"
+	"goworld-skeleton/internal/modules/bag"
+	"goworld-skeleton/internal/modules/wallet"
+)
+
+// LoginStatus reports a player's daily login counters.
+type LoginStatus struct {
+	PlayerID  string    `json:"player_id"`
+	Day       string    `json:"day"`
+	Streak    int       `json:"streak"`
+	TotalDays int       `json:"total_days"`
+	NextReset time.Time `json:"next_reset"`
+	// Reward is set only on the call that granted today's reward.
+	Reward   *dao.LoginReward  `json:"reward,omitempty"`
+	Calendar []dao.LoginReward `json:"calendar"`
+}
+
+// RecordLogin updates LastLogin and the level leaderboard and, on the first
+// login of a game day, the streak and day counters. That first login also
+// grants the day's calendar reward and publishes event.DailyLogin. Counters
+// and reward commit together, so a day is never rewarded twice.
+func (s Service) RecordLogin(playerID string) (LoginStatus, error) {
+	now := time.Now()
+	today := s.calendar.Day(now)
+	status := LoginStatus{PlayerID: playerID, Day: today, NextReset: s.calendar.NextReset(now)}
+
+	var level int
+	var err error
+	s.store.WithLock(func(store *dao.DataStore) {
+		original, ok := store.Players[playerID]
+		if !ok || original.Deleted() {
+			err = ErrPlayerNotFound
+			return
+		}
+
+		p := original
+		p.LastLogin = now
+		level = p.Level
+		if p.LoginDay != today {
+			if s.calendar.Consecutive(p.LoginDay, today) {
+				p.LoginStreak++
+			} else {
+				p.LoginStreak = 1
+			}
+			p.LoginDays++
+			p.LoginDay = today
+			if len(store.LoginCalendar) > 0 {
+				reward := store.LoginCalendar[(p.LoginStreak-1)%len(store.LoginCalendar)]
+				status.Reward = &reward
+			}
+		}
+		store.Players[p.ID] = p
+
+		if status.Reward != nil {
+			if err = s.grantLoginReward(store, p.ID, today, *status.Reward, now); err != nil {
+				store.Players[p.ID] = original
+				status.Reward = nil
+				return
+			}
+		}
+
+		status.Streak = p.LoginStreak
+		status.TotalDays = p.LoginDays
+		status.Calendar = append([]dao.LoginReward(nil), store.LoginCalendar...)
+	})
+	if err != nil {
+		return LoginStatus{}, err
+	}
+
+	s.boards.SetLevel(playerID, level)
+	if status.Reward != nil {
+		s.logger.Printf("player %s daily login %s, streak %d", playerID, today, status.Streak)
+		s.events.Publish(event.DailyLogin, playerID, event.DailyLoginPayload{Day: today, Streak: status.Streak, TotalDays: status.TotalDays})
+	}
+	return status, nil
+}
+
+// grantLoginReward puts the items of the reward in the bag, mailing what
+// does not fit, and credits its currency. Either both are granted or neither
+// is. Callers must hold the store write lock.
+func (s Service) grantLoginReward(store *dao.DataStore, playerID, day string, reward dao.LoginReward, now time.Time) error {
+	bagBefore, mailsBefore := store.Bags[playerID], store.Mails[playerID]
+	if len(reward.Items) > 0 {
+		_, err := s.bag.GiveLocked(store, playerID, bag.Grant{
+			Items:    reward.Items,
+			Overflow: bag.OverflowMail,
+			MailID:   fmt.Sprintf("login-%s-%s", playerID, day),
+			Subject:  fmt.Sprintf("每日登录奖励：第 %d 天", reward.Day),
+			Body:     "背包空间不足，登录奖励已随邮件发放。",
+		}, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(reward.Currency) > 0 {
+		changes := make([]wallet.Change, 0, len(reward.Currency))
+		for currency, amount := range reward.Currency {
+			changes = append(changes, wallet.Change{Currency: currency, Amount: amount})
+		}
+		meta := wallet.Meta{Reason: fmt.Sprintf("daily login day %d", reward.Day), Source: "player", RefID: "login:" + day}
+		if _, err := wallet.ApplyLocked(store, playerID, changes, meta, now); err != nil {
+			store.Bags[playerID], store.Mails[playerID] = bagBefore, mailsBefore
+			return err
+		}
+	}
+	return nil
+}
+
+// loginCalendar shows the caller's streak and the reward calendar.
+func (s Service) loginCalendar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+
+	playerID, err := auth.ResolvePlayer(r, "")
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	status := LoginStatus{PlayerID: playerID, Day: s.calendar.Day(now), NextReset: s.calendar.NextReset(now)}
+	s.store.WithRead(func(store *dao.DataStore) {
+		p := store.Players[playerID]
+		status.Streak = p.LoginStreak
+		status.TotalDays = p.LoginDays
+		status.Calendar = append([]dao.LoginReward(nil), store.LoginCalendar...)
+	})
+	writeJSON(w, http.StatusOK, status)
+}
@@ -0,0 +1,74 @@
+package player
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"goworld-skeleton/internal/auth"
+)
+
+// heartbeat keeps the caller's character online.
+func (s Service) heartbeat(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	playerID, err := auth.ResolvePlayer(r, "")
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.presence.Heartbeat(playerID))
+}
+
+// getPresence serves GET /api/player/:id/presence.
+func (s Service) getPresence(w http.ResponseWriter, r *http.Request, requested string) {
+	playerID, err := auth.ResolvePlayer(r, requested)
+	if errors.Is(err, auth.ErrPlayerMismatch) {
+		playerID, err = requested, nil
+	}
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+
+	if len(s.Summaries([]string{playerID})) == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "player not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.presence.Get(playerID))
+}
+
+// batchPresence handles POST /api/player/presence with {"ids": [...]}.
+func (s Service) batchPresence(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	if _, err := auth.ResolvePlayer(r, ""); err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var req struct {
+		IDs []string `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+		return
+	}
+	if len(req.IDs) == 0 || len(req.IDs) > batchLimit {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "ids must contain 1 to " + strconv.Itoa(batchLimit) + " entries"})
+		return
+	}
+
+	ids := make([]string, 0, len(req.IDs))
+	for _, summary := range s.Summaries(req.IDs) {
+		ids = append(ids, summary.ID)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"players": s.presence.Lookup(ids)})
+}
@@ -0,0 +1,181 @@
+package player
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"goworld-skeleton/internal/auth"
+	"goworld-skeleton/internal/config"
+	"goworld-skeleton/internal/dao"
+)
+
+var errNameTaken = errors.New("name is already taken")
+
+// RenameCooldownError is returned when a player renames again too soon.
+type RenameCooldownError struct {
+	RetryAt time.Time
+}
+
+func (e *RenameCooldownError) Error() string {
+	return "name was changed recently, retry after " + e.RetryAt.Format(time.RFC3339)
+}
+
+// validateName checks a name against the configured rules.
+func (s Service) validateName(name string) (string, error) {
+	return ValidateName(s.nameCfg, name)
+}
+
+// ValidateName trims the name and checks it against the length,
+// character-class and word rules of cfg.
+func ValidateName(cfg config.Names, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	length := utf8.RuneCountInString(name)
+	if length < cfg.MinLength || length > cfg.MaxLength {
+		return "", fmt.Errorf("name must be %d to %d characters", cfg.MinLength, cfg.MaxLength)
+	}
+
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' {
+			return "", errors.New("name may only contain letters, digits, '_' and '-'")
+		}
+	}
+
+	lower := strings.ToLower(name)
+	for _, word := range cfg.Reserved {
+		if lower == strings.ToLower(word) {
+			return "", errors.New("name is reserved")
+		}
+	}
+	for _, word := range cfg.Banned {
+		if strings.Contains(lower, strings.ToLower(word)) {
+			return "", errors.New("name contains a banned word")
+		}
+	}
+	return name, nil
+}
+
+// nameTaken reports whether another player already uses name, ignoring
+// case. Characters pending deletion keep their name. Callers must hold the
+// store lock.
+func nameTaken(store *dao.DataStore, name, exceptID string) bool {
+	for id, p := range store.Players {
+		if id != exceptID && strings.EqualFold(p.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// AvailableName returns preferred if it passes the name rules and no player
+// uses it yet. Otherwise it returns fallback, numbered "-2", "-3"... until
+// it is free. Callers must hold the store lock.
+func AvailableName(store *dao.DataStore, cfg config.Names, preferred, fallback string) string {
+	if name, err := ValidateName(cfg, preferred); err == nil && !nameTaken(store, name, "") {
+		return name
+	}
+	name := fallback
+	for n := 2; nameTaken(store, name, ""); n++ {
+		name = fmt.Sprintf("%s-%d", fallback, n)
+	}
+	return name
+}
+
+type profileInput struct {
+	Name string `json:"name"`
+}
+
+// updateProfile renames the selected character, subject to the name rules,
+// uniqueness and the rename cooldown.
+func (s Service) updateProfile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	playerID, err := auth.ResolvePlayer(r, "")
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var input profileInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	name, err := s.validateName(input.Name)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var updated dao.Player
+	var change dao.NameChange
+	s.store.WithLock(func(store *dao.DataStore) {
+		p, ok := store.Players[playerID]
+		if !ok || p.Deleted() {
+			err = ErrPlayerNotFound
+			return
+		}
+		if p.Name == name {
+			updated = p
+			return
+		}
+		now := time.Now()
+		if !p.RenamedAt.IsZero() && now.Before(p.RenamedAt.Add(s.nameCfg.RenameCooldown)) {
+			err = &RenameCooldownError{RetryAt: p.RenamedAt.Add(s.nameCfg.RenameCooldown)}
+			return
+		}
+		if nameTaken(store, name, p.ID) {
+			err = errNameTaken
+			return
+		}
+
+		change = dao.NameChange{PlayerID: p.ID, OldName: p.Name, NewName: name, ChangedAt: now}
+		store.NameHistory[p.ID] = append(store.NameHistory[p.ID], change)
+		p.Name = name
+		p.RenamedAt = now
+		store.Players[p.ID] = p
+		updated = p
+	})
+
+	var cooldown *RenameCooldownError
+	switch {
+	case errors.Is(err, ErrPlayerNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	case errors.As(err, &cooldown):
+		writeJSON(w, http.StatusTooManyRequests, map[string]string{"error": err.Error(), "retry_at": cooldown.RetryAt.Format(time.RFC3339)})
+		return
+	case err != nil:
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	}
+
+	if change.PlayerID != "" {
+		s.logger.Printf("player %s renamed from %q to %q", playerID, change.OldName, change.NewName)
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// nameHistory lists the renames of ?player_id= for support.
+func (s Service) nameHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+
+	playerID := r.URL.Query().Get("player_id")
+	history := make([]dao.NameChange, 0)
+	s.store.WithRead(func(store *dao.DataStore) {
+		history = append(history, store.NameHistory[playerID]...)
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{"player_id": playerID, "changes": history})
+}
@@ -0,0 +1,169 @@
+package player
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"goworld-skeleton/internal/auth"
+	"goworld-skeleton/internal/dao"
+)
+
+const (
+	defaultSearchPage = 20
+	maxSearchPage     = 50
+	// batchLimit caps the IDs accepted by one batch lookup.
+	batchLimit = 100
+)
+
+// Summary is the public view of a character. It is what other players see;
+// account, wallet and progression details stay private.
+type Summary struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	Level     int               `json:"level"`
+	Equipment map[string]string `json:"equipment,omitempty"`
+}
+
+func summaryOf(p dao.Player) Summary {
+	return Summary{ID: p.ID, Name: p.Name, Level: p.Level, Equipment: p.Equipment}
+}
+
+// Summaries returns public summaries for the given IDs in request order.
+// Unknown and deleted characters are skipped.
+func (s Service) Summaries(ids []string) []Summary {
+	summaries := make([]Summary, 0, len(ids))
+	s.store.WithRead(func(store *dao.DataStore) {
+		for _, id := range ids {
+			if p, ok := store.Players[id]; ok && !p.Deleted() {
+				summaries = append(summaries, summaryOf(p))
+			}
+		}
+	})
+	return summaries
+}
+
+// matchName reports whether name matches query. Prefix mode requires the
+// name to start with query; fuzzy mode also accepts a substring or the query
+// letters appearing in order. Both are case-insensitive.
+func matchName(name, query string, fuzzy bool) bool {
+	name, query = strings.ToLower(name), strings.ToLower(query)
+	if strings.HasPrefix(name, query) {
+		return true
+	}
+	if !fuzzy {
+		return false
+	}
+	if strings.Contains(name, query) {
+		return true
+	}
+	rest := []rune(query)
+	for _, r := range name {
+		if len(rest) > 0 && r == rest[0] {
+			rest = rest[1:]
+		}
+	}
+	return len(rest) == 0
+}
+
+// searchPlayers handles GET /api/player/search?q=&mode=prefix|fuzzy&offset=&limit=.
+func (s Service) searchPlayers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+
+	if _, err := auth.ResolvePlayer(r, ""); err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "q is required"})
+		return
+	}
+
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = "prefix"
+	}
+	if mode != "prefix" && mode != "fuzzy" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "mode must be prefix or fuzzy"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > maxSearchPage {
+		limit = defaultSearchPage
+	}
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+
+	var matches []Summary
+	s.store.WithRead(func(store *dao.DataStore) {
+		for _, p := range store.Players {
+			if !p.Deleted() && matchName(p.Name, query, mode == "fuzzy") {
+				matches = append(matches, summaryOf(p))
+			}
+		}
+	})
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := strings.ToLower(matches[i].Name), strings.ToLower(matches[j].Name)
+		if a != b {
+			return a < b
+		}
+		return matches[i].ID < matches[j].ID
+	})
+
+	page := []Summary{}
+	if offset < len(matches) {
+		page = matches[offset:min(offset+limit, len(matches))]
+	}
+	resp := map[string]interface{}{"players": page, "total": len(matches)}
+	if offset+limit < len(matches) {
+		resp["next_offset"] = offset + limit
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// batchProfiles handles POST /api/player/batch with {"ids": [...]}.
+func (s Service) batchProfiles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	if _, err := auth.ResolvePlayer(r, ""); err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var req struct {
+		IDs []string `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+		return
+	}
+	if len(req.IDs) == 0 || len(req.IDs) > batchLimit {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "ids must contain 1 to " + strconv.Itoa(batchLimit) + " entries"})
+		return
+	}
+
+	seen := make(map[string]bool, len(req.IDs))
+	ids := req.IDs[:0]
+	for _, id := range req.IDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"players": s.Summaries(ids)})
+}
@@ -2,27 +2,101 @@ package player
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strings"
 
+	"goworld-skeleton/internal/auth"
+	"goworld-skeleton/internal/config"
 	"goworld-skeleton/internal/dao"
+	"goworld-skeleton/internal/event"
+	"goworld-skeleton/internal/gameday"
+	"goworld-skeleton/internal/modules/bag"
+	"goworld-skeleton/internal/modules/leaderboard"
+	"goworld-skeleton/internal/presence"
+	cache "goworld-skeleton/internal/redis"
 )
 
 // Service exposes player endpoints.
 type Service struct {
-	store  *dao.DataStore
-	logger *log.Logger
+	store        *dao.DataStore
+	cache        *cache.Cache
+	sessions     *auth.Manager
+	characterCfg config.Characters
+	nameCfg      config.Names
+	calendar     gameday.Calendar
+	presence     *presence.Tracker
+	boards       leaderboard.Service
+	bag          bag.Service
+	events       *event.Bus
+	logger       *log.Logger
 }
 
-// NewService constructs a player service.
-func NewService(store *dao.DataStore, logger *log.Logger) Service {
-	return Service{store: store, logger: logger}
+// NewService constructs a player service and registers the item effects that
+// act on players. It records a login whenever a session starts acting as a
+// character. Level-ups and equipment changes drop the cached stat sheet, and
+// level-ups also move the player on the level leaderboard.
+func NewService(store *dao.DataStore, cache *cache.Cache, sessions *auth.Manager, cfg config.Config, events *event.Bus, tracker *presence.Tracker, boards leaderboard.Service, bags bag.Service, logger *log.Logger) Service {
+	s := Service{
+		store:        store,
+		cache:        cache,
+		sessions:     sessions,
+		characterCfg: cfg.Characters,
+		nameCfg:      cfg.Names,
+		calendar:     dailyCalendar(cfg.DailyReset, logger),
+		presence:     tracker,
+		boards:       boards,
+		bag:          bags,
+		events:       events,
+		logger:       logger,
+	}
+	bags.RegisterEffect(dao.EffectHeal, s.heal)
+	bags.RegisterEffect(dao.EffectGrantExp, s.grantExpEffect)
+	bags.RegisterEffect(dao.EffectBuff, s.applyBuff)
+	events.Subscribe(event.LevelUp, func(e event.Event) {
+		s.InvalidateStats(e.PlayerID)
+		if levelUp, ok := e.Payload.(event.LevelUpPayload); ok {
+			s.boards.SetLevel(e.PlayerID, levelUp.Level)
+		}
+	})
+	events.Subscribe(event.EquipmentChanged, func(e event.Event) {
+		s.InvalidateStats(e.PlayerID)
+	})
+	events.Subscribe(event.PlayerLogin, func(e event.Event) {
+		if _, err := s.RecordLogin(e.PlayerID); err != nil {
+			s.logger.Printf("record login for %s: %v", e.PlayerID, err)
+		}
+	})
+	return s
+}
+
+func dailyCalendar(cfg config.DailyReset, logger *log.Logger) gameday.Calendar {
+	calendar, err := gameday.Load(cfg.Timezone, cfg.Hour)
+	if err != nil {
+		logger.Printf("daily reset: %v", err)
+	}
+	return calendar
 }
 
 // Register binds HTTP endpoints.
 func (s Service) Register(mux *http.ServeMux) {
 	mux.HandleFunc("/api/player/", s.getProfile)
+	mux.HandleFunc("/api/player/characters", s.characters)
+	mux.HandleFunc("/api/player/characters/delete", s.deleteCharacter)
+	mux.HandleFunc("/api/player/characters/restore", s.restoreCharacter)
+	mux.HandleFunc("/api/player/characters/select", s.selectCharacter)
+	mux.HandleFunc("/api/player/profile", s.updateProfile)
+	mux.HandleFunc("/api/player/login-calendar", s.loginCalendar)
+	mux.HandleFunc("/api/player/search", s.searchPlayers)
+	mux.HandleFunc("/api/player/batch", s.batchProfiles)
+	mux.HandleFunc("/api/player/heartbeat", s.heartbeat)
+	mux.HandleFunc("/api/player/equip", s.equip)
+	mux.HandleFunc("/api/player/unequip", s.unequip)
+	mux.HandleFunc("/api/player/presence", s.batchPresence)
+	mux.HandleFunc("/api/admin/player/experience", s.adminGrantExperience)
+	mux.HandleFunc("/api/admin/player/damage", s.adminDamage)
+	mux.HandleFunc("/api/admin/player/names", s.nameHistory)
 }
 
 func (s Service) getProfile(w http.ResponseWriter, r *http.Request) {
@@ -31,7 +105,25 @@ func (s Service) getProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	playerID := strings.TrimPrefix(r.URL.Path, "/api/player/")
+	requested := strings.TrimPrefix(r.URL.Path, "/api/player/")
+	if id, ok := strings.CutSuffix(requested, "/stats"); ok {
+		s.getStats(w, r, id)
+		return
+	}
+	if id, ok := strings.CutSuffix(requested, "/presence"); ok {
+		s.getPresence(w, r, id)
+		return
+	}
+
+	playerID, err := auth.ResolvePlayer(r, requested)
+	if errors.Is(err, auth.ErrPlayerMismatch) {
+		s.getSummary(w, requested)
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
 	var player dao.Player
 	s.store.WithRead(func(store *dao.DataStore) {
 		player = store.Players[playerID]
@@ -46,6 +138,16 @@ func (s Service) getProfile(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, player)
 }
 
+// getSummary serves another player's profile, limited to public fields.
+func (s Service) getSummary(w http.ResponseWriter, playerID string) {
+	summaries := s.Summaries([]string{playerID})
+	if len(summaries) == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "player not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, summaries[0])
+}
+
 func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
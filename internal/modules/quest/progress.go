@@ -0,0 +1,145 @@
+package quest
+
+import (
+	"time"
+
+	"goworld-skeleton/internal/dao"
+	"goworld-skeleton/internal/event"
+)
+
+// advance feeds one gameplay event into every quest with a matching
+// condition and mode. Counters grow by amount; ConditionLevel keeps the
+// highest value seen. Quests reaching their target publish
+// event.QuestCompleted.
+func (s Service) advance(playerID, condition, mode string, amount int) {
+	now := time.Now()
+	var completed []event.QuestPayload
+	s.store.WithLock(func(store *dao.DataStore) {
+		if p, ok := store.Players[playerID]; !ok || p.Deleted() {
+			return
+		}
+		for _, def := range store.Quests {
+			if def.Condition != condition || (def.Mode != "" && def.Mode != mode) {
+				continue
+			}
+			progress := s.current(store, playerID, def, now)
+			if !progress.CompletedAt.IsZero() {
+				continue
+			}
+			if condition == dao.ConditionLevel {
+				progress.Progress = max(progress.Progress, amount)
+			} else {
+				progress.Progress += amount
+			}
+			if progress.Progress >= def.Target {
+				progress.Progress = def.Target
+				progress.CompletedAt = now
+				completed = append(completed, event.QuestPayload{QuestID: def.ID, Period: def.Period, Cycle: progress.Cycle})
+			}
+			save(store, playerID, progress)
+		}
+	})
+
+	for _, payload := range completed {
+		s.logger.Printf("player %s completed quest %s", playerID, payload.QuestID)
+		s.events.Publish(event.QuestCompleted, playerID, payload)
+	}
+}
+
+// RunResets clears expired daily and weekly progress at every game-day
+// boundary. Reads already ignore progress from an earlier cycle, so a missed
+// reset never shows stale progress. It blocks, so run it in its own
+// goroutine.
+func (s Service) RunResets() {
+	for {
+		time.Sleep(time.Until(s.calendar.NextReset(time.Now())))
+		s.resetExpired(time.Now())
+	}
+}
+
+func (s Service) resetExpired(now time.Time) {
+	cleared := 0
+	s.store.WithLock(func(store *dao.DataStore) {
+		for _, quests := range store.QuestProgress {
+			for id, progress := range quests {
+				def, ok := findQuest(store, id)
+				if !ok || progress.Cycle != s.cycle(def, now) {
+					delete(quests, id)
+					cleared++
+				}
+			}
+		}
+	})
+	s.logger.Printf("quest reset for %s cleared %d entries", s.calendar.Day(now), cleared)
+}
+
+// current returns the player's progress on def for the cycle containing now,
+// starting fresh when the stored progress belongs to an earlier cycle.
+// Callers must hold the store lock.
+func (s Service) current(store *dao.DataStore, playerID string, def dao.QuestDef, now time.Time) dao.QuestProgress {
+	cycle := s.cycle(def, now)
+	progress, ok := store.QuestProgress[playerID][def.ID]
+	if !ok || progress.Cycle != cycle {
+		return fresh(store, playerID, def, cycle)
+	}
+	return progress
+}
+
+// fresh starts progress on def. Level quests begin at the player's current
+// level, so levels gained before the quest was first evaluated still count.
+// Callers must hold the store lock.
+func fresh(store *dao.DataStore, playerID string, def dao.QuestDef, cycle string) dao.QuestProgress {
+	progress := dao.QuestProgress{QuestID: def.ID, Cycle: cycle}
+	if def.Condition == dao.ConditionLevel {
+		progress.Progress = min(store.Players[playerID].Level, def.Target)
+	}
+	return progress
+}
+
+// completed reports whether progress has reached def's target, including
+// level quests seeded at or past it that no event has completed yet.
+func completed(def dao.QuestDef, progress dao.QuestProgress) bool {
+	return !progress.CompletedAt.IsZero() || progress.Progress >= def.Target
+}
+
+// cycle names the game day or week def counts toward; lifetime quests have
+// a single unnamed cycle.
+func (s Service) cycle(def dao.QuestDef, now time.Time) string {
+	switch def.Period {
+	case dao.QuestDaily:
+		return s.calendar.Day(now)
+	case dao.QuestWeekly:
+		return s.calendar.Week(now)
+	default:
+		return ""
+	}
+}
+
+func (s Service) resetsAt(def dao.QuestDef, now time.Time) time.Time {
+	switch def.Period {
+	case dao.QuestDaily:
+		return s.calendar.NextReset(now)
+	case dao.QuestWeekly:
+		return s.calendar.NextWeeklyReset(now)
+	default:
+		return time.Time{}
+	}
+}
+
+// findQuest looks up a quest definition. Callers must hold the store lock.
+func findQuest(store *dao.DataStore, id string) (dao.QuestDef, bool) {
+	for _, def := range store.Quests {
+		if def.ID == id {
+			return def, true
+		}
+	}
+	return dao.QuestDef{}, false
+}
+
+// save stores progress. Callers must hold the store write lock.
+func save(store *dao.DataStore, playerID string, progress dao.QuestProgress) {
+	if store.QuestProgress[playerID] == nil {
+		store.QuestProgress[playerID] = map[string]dao.QuestProgress{}
+	}
+	store.QuestProgress[playerID][progress.QuestID] = progress
+}
@@ -0,0 +1,235 @@
+package quest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"goworld-skeleton/internal/auth"
+	"goworld-skeleton/internal/config"
+	"goworld-skeleton/internal/dao"
+	"goworld-skeleton/internal/event"
+	"goworld-skeleton/internal/gameday"
+	"goworld-skeleton/internal/modules/bag"
+	"goworld-skeleton/internal/modules/wallet"
+)
+
+var (
+	ErrQuestNotFound  = errors.New("quest not found")
+	ErrNotCompleted   = errors.New("quest not completed")
+	ErrAlreadyClaimed = errors.New("reward already claimed")
+)
+
+// Service tracks quest and achievement progress from gameplay events and
+// hands out their rewards.
+type Service struct {
+	store    *dao.DataStore
+	bag      bag.Service
+	calendar gameday.Calendar
+	events   *event.Bus
+	logger   *log.Logger
+}
+
+// NewService constructs a quest service and subscribes it to the events
+// that drive quest conditions.
+func NewService(store *dao.DataStore, bags bag.Service, cfg config.Config, events *event.Bus, logger *log.Logger) Service {
+	calendar, err := gameday.Load(cfg.DailyReset.Timezone, cfg.DailyReset.Hour)
+	if err != nil {
+		logger.Printf("quest reset: %v", err)
+	}
+
+	s := Service{store: store, bag: bags, calendar: calendar, events: events, logger: logger}
+	events.Subscribe(event.DailyLogin, func(e event.Event) {
+		s.advance(e.PlayerID, dao.ConditionLoginDays, "", 1)
+	})
+	events.Subscribe(event.ChatSent, func(e event.Event) {
+		s.advance(e.PlayerID, dao.ConditionChatMessages, "", 1)
+	})
+	events.Subscribe(event.MatchFinished, func(e event.Event) {
+		result, ok := e.Payload.(event.MatchPayload)
+		if !ok {
+			return
+		}
+		s.advance(e.PlayerID, dao.ConditionMatchesPlayed, result.Mode, 1)
+		if result.Won {
+			s.advance(e.PlayerID, dao.ConditionMatchWins, result.Mode, 1)
+		}
+	})
+	events.Subscribe(event.LevelUp, func(e event.Event) {
+		if levelUp, ok := e.Payload.(event.LevelUpPayload); ok {
+			s.advance(e.PlayerID, dao.ConditionLevel, "", levelUp.Level)
+		}
+	})
+	return s
+}
+
+// Register binds HTTP endpoints.
+func (s Service) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/api/quest", s.list)
+	mux.HandleFunc("/api/quest/claim", s.claim)
+}
+
+// View is a quest definition with the caller's progress in the current
+// cycle.
+type View struct {
+	dao.QuestDef
+	Cycle     string     `json:"cycle,omitempty"`
+	Progress  int        `json:"progress"`
+	Completed bool       `json:"completed"`
+	Claimed   bool       `json:"claimed"`
+	ResetsAt  *time.Time `json:"resets_at,omitempty"`
+}
+
+// list shows every quest, optionally filtered by ?period=daily|weekly|lifetime.
+func (s Service) list(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+
+	playerID, err := auth.ResolvePlayer(r, "")
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+
+	period := r.URL.Query().Get("period")
+	now := time.Now()
+	views := make([]View, 0)
+	s.store.WithRead(func(store *dao.DataStore) {
+		for _, def := range store.Quests {
+			if period != "" && def.Period != period {
+				continue
+			}
+			progress := s.current(store, playerID, def, now)
+			view := View{
+				QuestDef:  def,
+				Cycle:     progress.Cycle,
+				Progress:  progress.Progress,
+				Completed: completed(def, progress),
+				Claimed:   !progress.ClaimedAt.IsZero(),
+			}
+			if resetsAt := s.resetsAt(def, now); !resetsAt.IsZero() {
+				view.ResetsAt = &resetsAt
+			}
+			views = append(views, view)
+		}
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{"quests": views})
+}
+
+type claimInput struct {
+	QuestID string `json:"quest_id"`
+}
+
+// claim grants the reward of a completed quest once per cycle.
+func (s Service) claim(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	playerID, err := auth.ResolvePlayer(r, "")
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var input claimInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	var def dao.QuestDef
+	var progress dao.QuestProgress
+	var seeded bool
+	s.store.WithLock(func(store *dao.DataStore) {
+		var ok bool
+		if def, ok = findQuest(store, input.QuestID); !ok {
+			err = ErrQuestNotFound
+			return
+		}
+		progress = s.current(store, playerID, def, now)
+		switch {
+		case !completed(def, progress):
+			err = ErrNotCompleted
+			return
+		case !progress.ClaimedAt.IsZero():
+			err = ErrAlreadyClaimed
+			return
+		}
+		if err = s.grantReward(store, playerID, def, progress.Cycle, now); err != nil {
+			return
+		}
+		if seeded = progress.CompletedAt.IsZero(); seeded {
+			progress.CompletedAt = now
+		}
+		progress.ClaimedAt = now
+		save(store, playerID, progress)
+	})
+	switch {
+	case errors.Is(err, ErrQuestNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	case errors.Is(err, ErrNotCompleted), errors.Is(err, ErrAlreadyClaimed):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	case err != nil:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	if seeded {
+		s.events.Publish(event.QuestCompleted, playerID, event.QuestPayload{QuestID: def.ID, Period: def.Period, Cycle: progress.Cycle})
+	}
+	s.logger.Printf("player %s claimed quest %s %s", playerID, def.ID, progress.Cycle)
+	writeJSON(w, http.StatusOK, View{QuestDef: def, Cycle: progress.Cycle, Progress: progress.Progress, Completed: true, Claimed: true})
+}
+
+// grantReward puts the quest items in the bag, mailing what does not fit,
+// and credits the quest currency. Either both are granted or neither is.
+// Callers must hold the store write lock.
+func (s Service) grantReward(store *dao.DataStore, playerID string, def dao.QuestDef, cycle string, now time.Time) error {
+	refID := def.ID
+	if cycle != "" {
+		refID += ":" + cycle
+	}
+
+	bagBefore, mailsBefore := store.Bags[playerID], store.Mails[playerID]
+	if len(def.Items) > 0 {
+		_, err := s.bag.GiveLocked(store, playerID, bag.Grant{
+			Items:    def.Items,
+			Overflow: bag.OverflowMail,
+			MailID:   fmt.Sprintf("quest-%s-%s", playerID, refID),
+			Subject:  "任务奖励：" + def.Name,
+			Body:     "背包空间不足，任务奖励已随邮件发放。",
+		}, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(def.Currency) > 0 {
+		changes := make([]wallet.Change, 0, len(def.Currency))
+		for currency, amount := range def.Currency {
+			changes = append(changes, wallet.Change{Currency: currency, Amount: amount})
+		}
+		meta := wallet.Meta{Reason: "quest reward: " + def.Name, Source: "quest", RefID: refID}
+		if _, err := wallet.ApplyLocked(store, playerID, changes, meta, now); err != nil {
+			store.Bags[playerID], store.Mails[playerID] = bagBefore, mailsBefore
+			return err
+		}
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
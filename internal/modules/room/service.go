@@ -2,37 +2,56 @@ package room
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"slices"
 	"time"
 
+	"goworld-skeleton/internal/auth"
 	"goworld-skeleton/internal/dao"
+	"goworld-skeleton/internal/event"
+	"goworld-skeleton/internal/modules/friend"
+)
+
+var (
+	ErrRoomNotFound = errors.New("room not found")
+	ErrNotInvited   = errors.New("you are not invited to this room")
+	ErrNotSeated    = errors.New("you are not seated in this room")
+	ErrRoomFull     = errors.New("room is full")
+	ErrRoomClosed   = errors.New("room is no longer waiting for players")
 )
 
 // Service exposes lightweight room orchestration.
 type Service struct {
 	store  *dao.DataStore
+	events *event.Bus
 	logger *log.Logger
 }
 
 // NewService constructs a room service.
-func NewService(store *dao.DataStore, logger *log.Logger) Service {
-	return Service{store: store, logger: logger}
+func NewService(store *dao.DataStore, events *event.Bus, logger *log.Logger) Service {
+	return Service{store: store, events: events, logger: logger}
 }
 
 // Register binds HTTP endpoints.
 func (s Service) Register(mux *http.ServeMux) {
 	mux.HandleFunc("/api/room/create", s.create)
+	mux.HandleFunc("/api/room/join", s.join)
+	mux.HandleFunc("/api/room/leave", s.leave)
 	mux.HandleFunc("/api/room/", s.list)
 	mux.HandleFunc("/api/room", s.list)
 }
 
 type createInput struct {
-	Game       string   `json:"game"`
+	Game string `json:"game"`
+	// Players are invited; each takes a seat only by joining.
 	Players    []string `json:"players"`
 	MaxPlayers int      `json:"max_players"`
 }
 
+// create opens a room with the caller in the first seat and invites the
+// listed players.
 func (s Service) create(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.NotFound(w, r)
@@ -45,13 +64,153 @@ func (s Service) create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	room := dao.Room{ID: generateRoomID(), Game: input.Game, Players: input.Players, MaxPlayers: input.MaxPlayers, Status: "waiting"}
-	s.store.WithLock(func(store *dao.DataStore) { store.Rooms[room.ID] = room })
+	owner, err := auth.ResolvePlayer(r, "")
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+
+	room := dao.Room{ID: generateRoomID(), Game: input.Game, Players: []string{owner}, Invited: invitees(owner, input.Players), MaxPlayers: input.MaxPlayers, Status: "waiting"}
+	refused := ""
+	s.store.WithLock(func(store *dao.DataStore) {
+		for _, invited := range room.Invited {
+			if friend.Blocked(store, invited, owner) {
+				refused = invited
+				return
+			}
+		}
+		store.Rooms[room.ID] = room
+	})
+	if refused != "" {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": refused + ": " + friend.ErrBlocked.Error()})
+		return
+	}
 
 	s.logger.Printf("room %s created for %s", room.ID, room.Game)
+	s.events.Publish(event.RoomJoined, owner, event.RoomPayload{RoomID: room.ID, Game: room.Game})
 	writeJSON(w, http.StatusCreated, room)
 }
 
+type roomInput struct {
+	RoomID string `json:"room_id"`
+}
+
+// join seats the caller in a waiting room they were invited to.
+func (s Service) join(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	var input roomInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	playerID, err := auth.ResolvePlayer(r, "")
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var room dao.Room
+	s.store.WithLock(func(store *dao.DataStore) {
+		var ok bool
+		room, ok = store.Rooms[input.RoomID]
+		switch {
+		case !ok:
+			err = ErrRoomNotFound
+			return
+		case room.Status != "waiting":
+			err = ErrRoomClosed
+			return
+		case !slices.Contains(room.Invited, playerID):
+			err = ErrNotInvited
+			return
+		case room.MaxPlayers > 0 && len(room.Players) >= room.MaxPlayers:
+			err = ErrRoomFull
+			return
+		}
+		for _, seated := range room.Players {
+			if friend.Blocked(store, seated, playerID) || friend.Blocked(store, playerID, seated) {
+				err = friend.ErrBlocked
+				return
+			}
+		}
+		room.Invited = slices.DeleteFunc(slices.Clone(room.Invited), func(id string) bool { return id == playerID })
+		room.Players = append(slices.Clone(room.Players), playerID)
+		store.Rooms[room.ID] = room
+	})
+	switch {
+	case errors.Is(err, ErrRoomNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	case errors.Is(err, ErrNotInvited), errors.Is(err, friend.ErrBlocked):
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	case err != nil:
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.logger.Printf("room %s: %s joined", room.ID, playerID)
+	s.events.Publish(event.RoomJoined, playerID, event.RoomPayload{RoomID: room.ID, Game: room.Game})
+	writeJSON(w, http.StatusOK, room)
+}
+
+// leave gives up the caller's seat. A room nobody is seated in is closed.
+func (s Service) leave(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	var input roomInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	playerID, err := auth.ResolvePlayer(r, "")
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var room dao.Room
+	s.store.WithLock(func(store *dao.DataStore) {
+		var ok bool
+		room, ok = store.Rooms[input.RoomID]
+		switch {
+		case !ok:
+			err = ErrRoomNotFound
+			return
+		case !slices.Contains(room.Players, playerID):
+			err = ErrNotSeated
+			return
+		}
+		room.Players = slices.DeleteFunc(slices.Clone(room.Players), func(id string) bool { return id == playerID })
+		if len(room.Players) == 0 {
+			delete(store.Rooms, room.ID)
+			return
+		}
+		store.Rooms[room.ID] = room
+	})
+	switch {
+	case errors.Is(err, ErrRoomNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	case err != nil:
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.logger.Printf("room %s: %s left", room.ID, playerID)
+	s.events.Publish(event.RoomLeft, playerID, event.RoomPayload{RoomID: room.ID, Game: room.Game})
+	writeJSON(w, http.StatusOK, room)
+}
+
 func (s Service) list(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.NotFound(w, r)
@@ -66,6 +225,17 @@ func (s Service) list(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]interface{}{"rooms": rooms})
 }
 
+// invitees returns players without the owner or repeats.
+func invitees(owner string, players []string) []string {
+	invited := make([]string, 0, len(players))
+	for _, p := range players {
+		if p != owner && !slices.Contains(invited, p) {
+			invited = append(invited, p)
+		}
+	}
+	return invited
+}
+
 func generateRoomID() string {
 	return "room-" + time.Now().Format("150405.000")
 }
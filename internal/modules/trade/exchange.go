@@ -0,0 +1,127 @@
+package trade
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"goworld-skeleton/internal/dao"
+	"goworld-skeleton/internal/modules/bag"
+	"goworld-skeleton/internal/modules/wallet"
+)
+
+// validateOffer checks that the player can put up the items and currency
+// and returns them as an offer, with repeated items combined. Callers must
+// hold the store lock.
+func validateOffer(store *dao.DataStore, playerID string, items []dao.MailAttachment, currency map[string]int64) (dao.TradeOffer, error) {
+	p, ok := store.Players[playerID]
+	if !ok || p.Deleted() {
+		return dao.TradeOffer{}, ErrPlayerNotFound
+	}
+
+	offer := dao.TradeOffer{PlayerID: playerID, Items: make([]dao.MailAttachment, 0, len(items))}
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			return dao.TradeOffer{}, bag.ErrInvalidQuantity
+		}
+		def, ok := bag.FindItem(store, item.ItemID)
+		if !ok {
+			return dao.TradeOffer{}, fmt.Errorf("%w: %s", bag.ErrUnknownItem, item.ItemID)
+		}
+		if def.Bound {
+			return dao.TradeOffer{}, fmt.Errorf("%w: %s", ErrUntradeable, item.ItemID)
+		}
+		if i := slices.IndexFunc(offer.Items, func(a dao.MailAttachment) bool { return a.ItemID == item.ItemID }); i >= 0 {
+			offer.Items[i].Quantity += item.Quantity
+		} else {
+			offer.Items = append(offer.Items, item)
+		}
+	}
+	for _, item := range offer.Items {
+		if bag.Count(store, playerID, item.ItemID) < item.Quantity {
+			return dao.TradeOffer{}, fmt.Errorf("%w: %s", bag.ErrNotEnough, item.ItemID)
+		}
+	}
+
+	for code, amount := range currency {
+		if !slices.Contains(wallet.Currencies, code) {
+			return dao.TradeOffer{}, fmt.Errorf("%w: %s", wallet.ErrUnknownCurrency, code)
+		}
+		if amount < 0 {
+			return dao.TradeOffer{}, ErrInvalidAmount
+		}
+		if p.Wallet[code] < amount {
+			return dao.TradeOffer{}, fmt.Errorf("%w: %s", wallet.ErrInsufficientFunds, code)
+		}
+		if amount > 0 {
+			if offer.Currency == nil {
+				offer.Currency = make(map[string]int64, len(currency))
+			}
+			offer.Currency[code] = amount
+		}
+	}
+	return offer, nil
+}
+
+// execute swaps both offers and returns the record of the trade. Either the
+// whole swap happens or nothing changes. Callers must hold the store write
+// lock.
+func (s Service) execute(store *dao.DataStore, t dao.Trade, now time.Time) (dao.TradeRecord, error) {
+	var bags [2][]dao.BagEntry
+	var players [2]dao.Player
+	for i, offer := range t.Offers {
+		p, ok := store.Players[offer.PlayerID]
+		if !ok || p.Deleted() {
+			return dao.TradeRecord{}, ErrPlayerNotFound
+		}
+		players[i] = p
+		bags[i] = store.Bags[offer.PlayerID]
+	}
+	ledger := len(store.Ledger)
+
+	if err := s.swap(store, t, now); err != nil {
+		for i, offer := range t.Offers {
+			store.Players[offer.PlayerID] = players[i]
+			store.Bags[offer.PlayerID] = bags[i]
+		}
+		store.Ledger = store.Ledger[:ledger]
+		return dao.TradeRecord{}, err
+	}
+	return dao.TradeRecord{ID: t.ID, Offers: t.Offers, CompletedAt: now}, nil
+}
+
+// swap takes each side's items out of their bag, gives them to the other
+// side and settles the currency difference. It may fail halfway; execute
+// rolls back.
+func (s Service) swap(store *dao.DataStore, t dao.Trade, now time.Time) error {
+	for _, offer := range t.Offers {
+		for _, item := range offer.Items {
+			if err := bag.RemoveLocked(store, offer.PlayerID, item.ItemID, item.Quantity); err != nil {
+				return fmt.Errorf("%s: %w", offer.PlayerID, err)
+			}
+		}
+	}
+
+	for i, offer := range t.Offers {
+		other := t.Offers[1-i]
+		if len(other.Items) > 0 {
+			if _, err := s.bag.GiveLocked(store, offer.PlayerID, bag.Grant{Items: other.Items}, now); err != nil {
+				return fmt.Errorf("%s: %w", offer.PlayerID, err)
+			}
+		}
+
+		changes := make([]wallet.Change, 0, len(wallet.Currencies))
+		for _, code := range wallet.Currencies {
+			if delta := other.Currency[code] - offer.Currency[code]; delta != 0 {
+				changes = append(changes, wallet.Change{Currency: code, Amount: delta})
+			}
+		}
+		if len(changes) > 0 {
+			meta := wallet.Meta{Reason: "trade with " + other.PlayerID, Source: "trade", RefID: t.ID}
+			if _, err := wallet.ApplyLocked(store, offer.PlayerID, changes, meta, now); err != nil {
+				return fmt.Errorf("%s: %w", offer.PlayerID, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package trade
+
+import (
+	"errors"
+	"io"
+	"log"
+	"reflect"
+	"testing"
+	"time"
+
+	"goworld-skeleton/internal/dao"
+	"goworld-skeleton/internal/modules/bag"
+	"goworld-skeleton/internal/modules/wallet"
+)
+
+func TestExecuteRollsBack(t *testing.T) {
+	tests := []struct {
+		name    string
+		bags    [2][]dao.BagEntry
+		wallets [2]map[string]int64
+		offers  [2]dao.TradeOffer
+		err     error
+	}{
+		{
+			name: "partner bag full after currency settled",
+			bags: [2][]dao.BagEntry{
+				{{Slot: 0, ItemID: "sword", Quantity: 1}},
+				{{Slot: 0, ItemID: "potion", Quantity: 3}},
+			},
+			wallets: [2]map[string]int64{{dao.CurrencyGold: 100}, {dao.CurrencyGold: 500}},
+			offers: [2]dao.TradeOffer{
+				{Items: []dao.MailAttachment{{ItemID: "sword", Quantity: 1}}},
+				{Currency: map[string]int64{dao.CurrencyGold: 200}},
+			},
+			err: bag.ErrBagFull,
+		},
+		{
+			name: "partner spent the gold after locking",
+			bags: [2][]dao.BagEntry{
+				{{Slot: 0, ItemID: "sword", Quantity: 1}},
+				nil,
+			},
+			wallets: [2]map[string]int64{{dao.CurrencyGold: 100}, {dao.CurrencyGold: 50}},
+			offers: [2]dao.TradeOffer{
+				{Items: []dao.MailAttachment{{ItemID: "sword", Quantity: 1}}},
+				{Currency: map[string]int64{dao.CurrencyGold: 200}},
+			},
+			err: wallet.ErrInsufficientFunds,
+		},
+		{
+			name: "second side no longer holds the items",
+			bags: [2][]dao.BagEntry{
+				{{Slot: 0, ItemID: "sword", Quantity: 1}},
+				{{Slot: 0, ItemID: "potion", Quantity: 1}},
+			},
+			wallets: [2]map[string]int64{{dao.CurrencyGold: 100}, {dao.CurrencyGold: 100}},
+			offers: [2]dao.TradeOffer{
+				{Items: []dao.MailAttachment{{ItemID: "sword", Quantity: 1}}},
+				{Items: []dao.MailAttachment{{ItemID: "potion", Quantity: 2}}},
+			},
+			err: bag.ErrNotEnough,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := dao.NewDataStore()
+			s := NewService(store, bag.NewService(store, 1, log.New(io.Discard, "", 0)), time.Minute, log.New(io.Discard, "", 0))
+
+			ids := [2]string{"trader-a", "trader-b"}
+			trade := dao.Trade{ID: "trade-test"}
+			for i, id := range ids {
+				store.Players[id] = dao.Player{ID: id, Name: id, Wallet: tt.wallets[i]}
+				store.Bags[id] = tt.bags[i]
+				trade.Offers[i] = tt.offers[i]
+				trade.Offers[i].PlayerID = id
+			}
+			players := [2]dao.Player{store.Players[ids[0]], store.Players[ids[1]]}
+			ledger := append([]dao.LedgerEntry(nil), store.Ledger...)
+
+			_, err := s.execute(store, trade, time.Now())
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("execute error = %v, want %v", err, tt.err)
+			}
+			for i, id := range ids {
+				if got := store.Bags[id]; !reflect.DeepEqual(got, tt.bags[i]) {
+					t.Errorf("bag of %s = %v, want %v", id, got, tt.bags[i])
+				}
+				if got := store.Players[id]; !reflect.DeepEqual(got.Wallet, players[i].Wallet) {
+					t.Errorf("wallet of %s = %v, want %v", id, got.Wallet, players[i].Wallet)
+				}
+			}
+			if !reflect.DeepEqual(store.Ledger, ledger) {
+				t.Errorf("ledger = %v, want %v", store.Ledger, ledger)
+			}
+		})
+	}
+}
@@ -0,0 +1,353 @@
+package trade
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"goworld-skeleton/internal/auth"
+	"goworld-skeleton/internal/dao"
+	"goworld-skeleton/internal/modules/bag"
+	"goworld-skeleton/internal/modules/friend"
+	"goworld-skeleton/internal/modules/wallet"
+)
+
+var (
+	ErrPlayerNotFound = errors.New("player not found")
+	ErrSelf           = errors.New("cannot trade with yourself")
+	ErrNoTarget       = errors.New("player_id is required")
+	ErrBusy           = errors.New("you are already trading")
+	ErrPartnerBusy    = errors.New("that player is already trading")
+	ErrNoTrade        = errors.New("no open trade")
+	ErrUntradeable    = errors.New("item cannot be traded")
+	ErrNotLocked      = errors.New("both offers must be locked first")
+	ErrNotAccepted    = errors.New("the other player has not accepted the trade")
+	ErrNotInvited     = errors.New("only the invited player can accept the trade")
+	ErrInvalidAmount  = errors.New("amount must be positive")
+)
+
+// Service runs player-to-player trades. A trade moves through accept, offer,
+// lock and confirm; once both sides confirm, items and currency swap in one
+// step. Trades left idle for longer than the idle timeout are abandoned.
+type Service struct {
+	store  *dao.DataStore
+	bag    bag.Service
+	idle   time.Duration
+	logger *log.Logger
+}
+
+// NewService constructs a trade service. An idleTimeout of zero keeps
+// trades open until cancelled.
+func NewService(store *dao.DataStore, bags bag.Service, idleTimeout time.Duration, logger *log.Logger) Service {
+	return Service{store: store, bag: bags, idle: idleTimeout, logger: logger}
+}
+
+// Register binds HTTP endpoints.
+func (s Service) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/api/trade", s.current)
+	mux.HandleFunc("/api/trade/open", s.open)
+	mux.HandleFunc("/api/trade/accept", s.accept)
+	mux.HandleFunc("/api/trade/offer", s.offer)
+	mux.HandleFunc("/api/trade/lock", s.lock)
+	mux.HandleFunc("/api/trade/confirm", s.confirm)
+	mux.HandleFunc("/api/trade/cancel", s.cancel)
+	mux.HandleFunc("/api/admin/trade/log", s.tradeLog)
+}
+
+// Abandon drops any open trade the player is part of. Callers must hold the
+// store write lock.
+func Abandon(store *dao.DataStore, playerID string) {
+	if t, ok := find(store, playerID); ok {
+		delete(store.Trades, t.ID)
+	}
+}
+
+// find returns the open trade the player is part of. Callers must hold the
+// store lock.
+func find(store *dao.DataStore, playerID string) (dao.Trade, bool) {
+	for _, t := range store.Trades {
+		if t.Offers[0].PlayerID == playerID || t.Offers[1].PlayerID == playerID {
+			return t, true
+		}
+	}
+	return dao.Trade{}, false
+}
+
+// active returns the player's open trade, abandoning it instead once it has
+// sat idle for the idle timeout. Callers must hold the store write lock.
+func (s Service) active(store *dao.DataStore, playerID string, now time.Time) (dao.Trade, bool) {
+	t, ok := find(store, playerID)
+	if ok && s.expired(t, now) {
+		delete(store.Trades, t.ID)
+		s.logger.Printf("trade %s abandoned after %s idle", t.ID, s.idle)
+		return dao.Trade{}, false
+	}
+	return t, ok
+}
+
+func (s Service) expired(t dao.Trade, now time.Time) bool {
+	return s.idle > 0 && now.Sub(t.UpdatedAt) >= s.idle
+}
+
+// accepted returns the player's open trade once both sides have agreed to
+// it. Callers must hold the store write lock.
+func (s Service) accepted(store *dao.DataStore, playerID string, now time.Time) (dao.Trade, error) {
+	t, ok := s.active(store, playerID, now)
+	switch {
+	case !ok:
+		return dao.Trade{}, ErrNoTrade
+	case !t.Accepted:
+		return dao.Trade{}, ErrNotAccepted
+	}
+	return t, nil
+}
+
+// side is the index of the player's offer in t.
+func side(t dao.Trade, playerID string) int {
+	if t.Offers[0].PlayerID == playerID {
+		return 0
+	}
+	return 1
+}
+
+func (s Service) current(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+
+	playerID, err := auth.ResolvePlayer(r, "")
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var t dao.Trade
+	var ok bool
+	s.store.WithRead(func(store *dao.DataStore) {
+		t, ok = find(store, playerID)
+		ok = ok && !s.expired(t, time.Now())
+	})
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": ErrNoTrade.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+type tradeInput struct {
+	PlayerID string               `json:"player_id"`
+	Items    []dao.MailAttachment `json:"items"`
+	Currency map[string]int64     `json:"currency"`
+}
+
+// open invites player_id to trade. The trade starts once they accept.
+func (s Service) open(w http.ResponseWriter, r *http.Request) {
+	s.handle(w, r, func(store *dao.DataStore, self string, input tradeInput, now time.Time) (interface{}, error) {
+		switch {
+		case input.PlayerID == "":
+			return nil, ErrNoTarget
+		case input.PlayerID == self:
+			return nil, ErrSelf
+		}
+		if p, ok := store.Players[input.PlayerID]; !ok || p.Deleted() {
+			return nil, ErrPlayerNotFound
+		}
+		if friend.Blocked(store, input.PlayerID, self) || friend.Blocked(store, self, input.PlayerID) {
+			return nil, friend.ErrBlocked
+		}
+		if _, ok := s.active(store, self, now); ok {
+			return nil, ErrBusy
+		}
+		if _, ok := s.active(store, input.PlayerID, now); ok {
+			return nil, ErrPartnerBusy
+		}
+
+		t := dao.Trade{
+			ID:        fmt.Sprintf("trade-%s-%d", self, now.UnixNano()),
+			Offers:    [2]dao.TradeOffer{{PlayerID: self, Items: []dao.MailAttachment{}}, {PlayerID: input.PlayerID, Items: []dao.MailAttachment{}}},
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		store.Trades[t.ID] = t
+		return t, nil
+	})
+}
+
+// accept lets the invited player agree to a trade opened with them.
+// Declining is a cancel.
+func (s Service) accept(w http.ResponseWriter, r *http.Request) {
+	s.handle(w, r, func(store *dao.DataStore, self string, _ tradeInput, now time.Time) (interface{}, error) {
+		t, ok := s.active(store, self, now)
+		if !ok {
+			return nil, ErrNoTrade
+		}
+		if side(t, self) != 1 {
+			return nil, ErrNotInvited
+		}
+		t.Accepted = true
+		t.UpdatedAt = now
+		store.Trades[t.ID] = t
+		return t, nil
+	})
+}
+
+// offer replaces the caller's offer. It unlocks the caller's side and clears
+// both confirmations.
+func (s Service) offer(w http.ResponseWriter, r *http.Request) {
+	s.handle(w, r, func(store *dao.DataStore, self string, input tradeInput, now time.Time) (interface{}, error) {
+		t, err := s.accepted(store, self, now)
+		if err != nil {
+			return nil, err
+		}
+		offer, err := validateOffer(store, self, input.Items, input.Currency)
+		if err != nil {
+			return nil, err
+		}
+
+		i := side(t, self)
+		t.Offers[i] = offer
+		t.Locked[i] = false
+		t.Confirmed = [2]bool{}
+		t.UpdatedAt = now
+		store.Trades[t.ID] = t
+		return t, nil
+	})
+}
+
+// lock marks the caller's offer as final.
+func (s Service) lock(w http.ResponseWriter, r *http.Request) {
+	s.handle(w, r, func(store *dao.DataStore, self string, _ tradeInput, now time.Time) (interface{}, error) {
+		t, err := s.accepted(store, self, now)
+		if err != nil {
+			return nil, err
+		}
+		t.Locked[side(t, self)] = true
+		t.UpdatedAt = now
+		store.Trades[t.ID] = t
+		return t, nil
+	})
+}
+
+// confirm accepts the locked offers. The second confirmation executes the
+// trade.
+func (s Service) confirm(w http.ResponseWriter, r *http.Request) {
+	s.handle(w, r, func(store *dao.DataStore, self string, _ tradeInput, now time.Time) (interface{}, error) {
+		t, err := s.accepted(store, self, now)
+		if err != nil {
+			return nil, err
+		}
+		if !t.Locked[0] || !t.Locked[1] {
+			return nil, ErrNotLocked
+		}
+
+		t.Confirmed[side(t, self)] = true
+		t.UpdatedAt = now
+		if !t.Confirmed[0] || !t.Confirmed[1] {
+			store.Trades[t.ID] = t
+			return t, nil
+		}
+
+		record, err := s.execute(store, t, now)
+		if err != nil {
+			t.Confirmed = [2]bool{}
+			store.Trades[t.ID] = t
+			return nil, err
+		}
+		delete(store.Trades, t.ID)
+		store.TradeLog = append(store.TradeLog, record)
+		s.logger.Printf("trade %s completed: %s gave %v %v, %s gave %v %v", record.ID,
+			record.Offers[0].PlayerID, record.Offers[0].Items, record.Offers[0].Currency,
+			record.Offers[1].PlayerID, record.Offers[1].Items, record.Offers[1].Currency)
+		return map[string]interface{}{"status": "completed", "trade": record}, nil
+	})
+}
+
+// cancel ends the caller's open trade, or declines an invitation, without
+// exchanging anything.
+func (s Service) cancel(w http.ResponseWriter, r *http.Request) {
+	s.handle(w, r, func(store *dao.DataStore, self string, _ tradeInput, now time.Time) (interface{}, error) {
+		t, ok := s.active(store, self, now)
+		if !ok {
+			return nil, ErrNoTrade
+		}
+		delete(store.Trades, t.ID)
+		return map[string]string{"status": "cancelled", "trade_id": t.ID}, nil
+	})
+}
+
+// tradeLog lists completed trades for fraud review, optionally only those
+// involving ?player_id=.
+func (s Service) tradeLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+
+	playerID := r.URL.Query().Get("player_id")
+	records := make([]dao.TradeRecord, 0)
+	s.store.WithRead(func(store *dao.DataStore) {
+		for _, record := range store.TradeLog {
+			if playerID == "" || record.Offers[0].PlayerID == playerID || record.Offers[1].PlayerID == playerID {
+				records = append(records, record)
+			}
+		}
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{"trades": records})
+}
+
+// handle decodes a tradeInput for the selected character and runs action
+// under the store lock.
+func (s Service) handle(w http.ResponseWriter, r *http.Request, action func(store *dao.DataStore, self string, input tradeInput, now time.Time) (interface{}, error)) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	self, err := auth.ResolvePlayer(r, "")
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var input tradeInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var result interface{}
+	s.store.WithLock(func(store *dao.DataStore) {
+		result, err = action(store, self, input, time.Now())
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.logger.Printf("trade %s: %s", r.URL.Path, self)
+	writeJSON(w, http.StatusOK, result)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrPlayerNotFound), errors.Is(err, ErrNoTrade), errors.Is(err, bag.ErrUnknownItem):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, friend.ErrBlocked), errors.Is(err, ErrNotInvited):
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+	case errors.Is(err, ErrBusy), errors.Is(err, ErrPartnerBusy), errors.Is(err, ErrNotLocked), errors.Is(err, ErrNotAccepted),
+		errors.Is(err, bag.ErrBagFull), errors.Is(err, bag.ErrNotEnough), errors.Is(err, wallet.ErrInsufficientFunds):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
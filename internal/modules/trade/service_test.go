@@ -0,0 +1,52 @@
+package trade
+
+import (
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"goworld-skeleton/internal/dao"
+	"goworld-skeleton/internal/modules/bag"
+)
+
+func TestAccepted(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		idle     time.Duration
+		accepted bool
+		updated  time.Time
+		err      error
+		kept     bool
+	}{
+		{name: "accepted", idle: time.Minute, accepted: true, updated: now, kept: true},
+		{name: "awaiting the partner", idle: time.Minute, updated: now, err: ErrNotAccepted, kept: true},
+		{name: "just under the timeout", idle: time.Minute, accepted: true, updated: now.Add(-time.Minute + time.Second), kept: true},
+		{name: "idle past the timeout", idle: time.Minute, accepted: true, updated: now.Add(-time.Minute), err: ErrNoTrade},
+		{name: "unanswered invitation expires", idle: time.Minute, updated: now.Add(-time.Hour), err: ErrNoTrade},
+		{name: "no timeout", accepted: true, updated: now.Add(-24 * time.Hour), kept: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := dao.NewDataStore()
+			logger := log.New(io.Discard, "", 0)
+			s := NewService(store, bag.NewService(store, 1, logger), tt.idle, logger)
+			store.Trades["trade-test"] = dao.Trade{
+				ID:        "trade-test",
+				Accepted:  tt.accepted,
+				Offers:    [2]dao.TradeOffer{{PlayerID: "trader-a"}, {PlayerID: "trader-b"}},
+				CreatedAt: tt.updated,
+				UpdatedAt: tt.updated,
+			}
+
+			if _, err := s.accepted(store, "trader-b", now); !errors.Is(err, tt.err) {
+				t.Errorf("accepted error = %v, want %v", err, tt.err)
+			}
+			if _, ok := store.Trades["trade-test"]; ok != tt.kept {
+				t.Errorf("trade kept = %v, want %v", ok, tt.kept)
+			}
+		})
+	}
+}
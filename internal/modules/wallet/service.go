@@ -0,0 +1,297 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"goworld-skeleton/internal/auth"
+	"goworld-skeleton/internal/dao"
+)
+
+const (
+	defaultLedgerPage = 50
+	maxLedgerPage     = 200
+)
+
+var (
+	ErrPlayerNotFound    = errors.New("player not found")
+	ErrUnknownCurrency   = errors.New("unknown currency")
+	ErrInvalidAmount     = errors.New("amount must be non-zero")
+	ErrNotPositive       = errors.New("amount must be positive")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+)
+
+// Currencies lists every currency a wallet can hold.
+var Currencies = []string{dao.CurrencyGold, dao.CurrencyDiamond}
+
+// Change is a signed amount of one currency; negative amounts are debits.
+type Change struct {
+	Currency string `json:"currency"`
+	Amount   int64  `json:"amount"`
+}
+
+// Meta says why a wallet changed. Source names the module making the change
+// and RefID points at the record in that module, e.g. a trade or mail ID.
+type Meta struct {
+	Reason string
+	Source string
+	RefID  string
+}
+
+// Service exposes player currency wallets backed by an append-only ledger.
+type Service struct {
+	store  *dao.DataStore
+	logger *log.Logger
+}
+
+// NewService constructs a wallet service.
+func NewService(store *dao.DataStore, logger *log.Logger) Service {
+	return Service{store: store, logger: logger}
+}
+
+// Register binds HTTP endpoints.
+func (s Service) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/api/wallet", s.balances)
+	mux.HandleFunc("/api/wallet/ledger", s.ownLedger)
+	mux.HandleFunc("/api/admin/wallet/adjust", s.adjust)
+	mux.HandleFunc("/api/admin/wallet/ledger", s.playerLedger)
+}
+
+// Credit adds amount of currency to the player's wallet. Amount must be
+// positive; use Apply for signed changes.
+func (s Service) Credit(playerID, currency string, amount int64, meta Meta) (int64, error) {
+	if amount <= 0 {
+		return 0, ErrNotPositive
+	}
+	return s.single(playerID, Change{Currency: currency, Amount: amount}, meta)
+}
+
+// Debit removes amount of currency, failing if the balance would go negative.
+// Amount must be positive.
+func (s Service) Debit(playerID, currency string, amount int64, meta Meta) (int64, error) {
+	if amount <= 0 {
+		return 0, ErrNotPositive
+	}
+	return s.single(playerID, Change{Currency: currency, Amount: -amount}, meta)
+}
+
+func (s Service) single(playerID string, change Change, meta Meta) (int64, error) {
+	entries, err := s.Apply(playerID, []Change{change}, meta)
+	if err != nil {
+		return 0, err
+	}
+	return entries[0].Balance, nil
+}
+
+// Apply performs all changes atomically: either every change is applied and
+// recorded in the ledger, or none is.
+func (s Service) Apply(playerID string, changes []Change, meta Meta) ([]dao.LedgerEntry, error) {
+	var entries []dao.LedgerEntry
+	var err error
+	s.store.WithLock(func(store *dao.DataStore) {
+		entries, err = ApplyLocked(store, playerID, changes, meta, time.Now())
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		s.logger.Printf("wallet %s %s %+d -> %d (%s/%s)", playerID, e.Currency, e.Delta, e.Balance, meta.Source, meta.Reason)
+	}
+	return entries, nil
+}
+
+// ApplyLocked is Apply for callers that already hold the store write lock,
+// so a wallet change can commit together with other changes.
+func ApplyLocked(store *dao.DataStore, playerID string, changes []Change, meta Meta, now time.Time) ([]dao.LedgerEntry, error) {
+	p, ok := store.Players[playerID]
+	if !ok || p.Deleted() {
+		return nil, ErrPlayerNotFound
+	}
+
+	wallet := make(map[string]int64, len(Currencies))
+	for currency, balance := range p.Wallet {
+		wallet[currency] = balance
+	}
+
+	for _, change := range changes {
+		if !known(change.Currency) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownCurrency, change.Currency)
+		}
+		if change.Amount == 0 {
+			return nil, ErrInvalidAmount
+		}
+		balance := wallet[change.Currency]
+		if change.Amount > 0 && balance > math.MaxInt64-change.Amount {
+			return nil, errors.New("balance overflow")
+		}
+		if balance+change.Amount < 0 {
+			return nil, fmt.Errorf("%w: %s", ErrInsufficientFunds, change.Currency)
+		}
+		wallet[change.Currency] = balance + change.Amount
+	}
+
+	entries := make([]dao.LedgerEntry, 0, len(changes))
+	running := make(map[string]int64, len(Currencies))
+	for currency, balance := range p.Wallet {
+		running[currency] = balance
+	}
+	for _, change := range changes {
+		running[change.Currency] += change.Amount
+		entry := dao.LedgerEntry{
+			ID:       "L" + strconv.Itoa(len(store.Ledger)+1),
+			PlayerID: playerID,
+			Currency: change.Currency,
+			Delta:    change.Amount,
+			Balance:  running[change.Currency],
+			Reason:   meta.Reason,
+			Source:   meta.Source,
+			RefID:    meta.RefID,
+			At:       now,
+		}
+		store.Ledger = append(store.Ledger, entry)
+		entries = append(entries, entry)
+	}
+
+	p.Wallet = wallet
+	store.Players[playerID] = p
+	return entries, nil
+}
+
+// Balances returns every currency of the player's wallet, including zeros.
+func Balances(p dao.Player) map[string]int64 {
+	balances := make(map[string]int64, len(Currencies))
+	for _, currency := range Currencies {
+		balances[currency] = p.Wallet[currency]
+	}
+	return balances
+}
+
+func known(currency string) bool {
+	for _, c := range Currencies {
+		if c == currency {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Service) balances(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+
+	playerID, err := auth.ResolvePlayer(r, "")
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var p dao.Player
+	s.store.WithRead(func(store *dao.DataStore) { p = store.Players[playerID] })
+	writeJSON(w, http.StatusOK, map[string]interface{}{"player_id": playerID, "balances": Balances(p)})
+}
+
+func (s Service) ownLedger(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+
+	playerID, err := auth.ResolvePlayer(r, "")
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+	s.writeLedger(w, r, playerID)
+}
+
+func (s Service) playerLedger(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+	s.writeLedger(w, r, r.URL.Query().Get("player_id"))
+}
+
+// writeLedger pages through the player's entries newest first using
+// ?limit= and ?offset=.
+func (s Service) writeLedger(w http.ResponseWriter, r *http.Request, playerID string) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > maxLedgerPage {
+		limit = defaultLedgerPage
+	}
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+
+	entries := make([]dao.LedgerEntry, 0, limit)
+	skipped := 0
+	s.store.WithRead(func(store *dao.DataStore) {
+		for i := len(store.Ledger) - 1; i >= 0 && len(entries) < limit; i-- {
+			if store.Ledger[i].PlayerID != playerID {
+				continue
+			}
+			if skipped < offset {
+				skipped++
+				continue
+			}
+			entries = append(entries, store.Ledger[i])
+		}
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{"player_id": playerID, "entries": entries})
+}
+
+type adjustInput struct {
+	PlayerID string `json:"player_id"`
+	Currency string `json:"currency"`
+	Amount   int64  `json:"amount"`
+	Reason   string `json:"reason"`
+}
+
+// adjust lets ops credit (positive amount) or debit (negative) a wallet.
+func (s Service) adjust(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	var input adjustInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if input.Reason == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "reason required"})
+		return
+	}
+
+	balance, err := s.single(input.PlayerID, Change{Currency: input.Currency, Amount: input.Amount}, Meta{
+		Reason: input.Reason,
+		Source: "admin",
+		RefID:  auth.Operator(r),
+	})
+	switch {
+	case errors.Is(err, ErrPlayerNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, ErrInsufficientFunds):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+	case err != nil:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	default:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"player_id": input.PlayerID, "currency": input.Currency, "balance": balance})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
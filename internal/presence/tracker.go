@@ -0,0 +1,192 @@
+package presence
+
+import (
+	"sync"
+	"time"
+
+	"goworld-skeleton/internal/event"
+	cache "goworld-skeleton/internal/redis"
+)
+
+// Player states.
+const (
+	Offline = "offline"
+	Online  = "online"
+	InRoom  = "in_room"
+)
+
+const keyPrefix = "presence:"
+
+// Status is a player's presence as seen by others.
+type Status struct {
+	PlayerID string    `json:"player_id"`
+	State    string    `json:"state"`
+	RoomID   string    `json:"room_id,omitempty"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// Tracker keeps presence records in the cache under "presence:<playerID>".
+// A player counts as online while heartbeats arrive within timeout; records
+// are kept for retention so last-seen survives going offline.
+type Tracker struct {
+	mu        sync.Mutex
+	cache     *cache.Cache
+	events    *event.Bus
+	timeout   time.Duration
+	retention time.Duration
+}
+
+// NewTracker constructs a tracker. Logins mark a player online, logouts mark
+// them offline and room joins mark them in a room until they leave it or its
+// match finishes.
+func NewTracker(cache *cache.Cache, events *event.Bus, timeout, retention time.Duration) *Tracker {
+	t := &Tracker{cache: cache, events: events, timeout: timeout, retention: retention}
+	events.Subscribe(event.PlayerLogin, func(e event.Event) { t.Heartbeat(e.PlayerID) })
+	events.Subscribe(event.PlayerLogout, func(e event.Event) { t.Disconnect(e.PlayerID) })
+	events.Subscribe(event.RoomJoined, func(e event.Event) {
+		if payload, ok := e.Payload.(event.RoomPayload); ok {
+			t.EnterRoom(e.PlayerID, payload.RoomID)
+		}
+	})
+	events.Subscribe(event.RoomLeft, func(e event.Event) {
+		if payload, ok := e.Payload.(event.RoomPayload); ok {
+			t.LeaveRoom(e.PlayerID, payload.RoomID)
+		}
+	})
+	events.Subscribe(event.MatchFinished, func(e event.Event) {
+		if payload, ok := e.Payload.(event.MatchPayload); ok {
+			t.LeaveRoom(e.PlayerID, payload.MatchID)
+		}
+	})
+	return t
+}
+
+// Heartbeat records that the player is connected, keeping any room.
+func (t *Tracker) Heartbeat(playerID string) Status {
+	return t.update(playerID, func(s *Status) {
+		if s.State != InRoom {
+			s.State = Online
+		}
+	})
+}
+
+// EnterRoom marks a connected player as seated in roomID. Offline players
+// stay offline.
+func (t *Tracker) EnterRoom(playerID, roomID string) Status {
+	return t.update(playerID, func(s *Status) {
+		if s.State == Offline {
+			return
+		}
+		s.State = InRoom
+		s.RoomID = roomID
+	})
+}
+
+// LeaveRoom moves a player seated in roomID back to online. Players in
+// another room or offline are left as they are.
+func (t *Tracker) LeaveRoom(playerID, roomID string) Status {
+	return t.update(playerID, func(s *Status) {
+		if s.State != InRoom || s.RoomID != roomID {
+			return
+		}
+		s.State = Online
+		s.RoomID = ""
+	})
+}
+
+// Disconnect marks the player offline immediately.
+func (t *Tracker) Disconnect(playerID string) Status {
+	return t.update(playerID, func(s *Status) {
+		s.State = Offline
+		s.RoomID = ""
+	})
+}
+
+// Forget deletes the player's presence record, last-seen time included.
+func (t *Tracker) Forget(playerID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cache.Delete(keyPrefix + playerID)
+}
+
+// Get returns the player's presence. Players who stopped heartbeating read
+// as offline even before the sweeper has recorded it.
+func (t *Tracker) Get(playerID string) Status {
+	return t.load(playerID, time.Now())
+}
+
+// Lookup returns presence for each ID in order.
+func (t *Tracker) Lookup(ids []string) []Status {
+	now := time.Now()
+	statuses := make([]Status, 0, len(ids))
+	for _, id := range ids {
+		statuses = append(statuses, t.load(id, now))
+	}
+	return statuses
+}
+
+// Sweep marks players whose heartbeats stopped as offline, checking every
+// interval. It blocks, so run it in its own goroutine.
+func (t *Tracker) Sweep(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		t.expire(now)
+	}
+}
+
+func (t *Tracker) expire(now time.Time) {
+	var expired []Status
+	t.mu.Lock()
+	for _, key := range t.cache.Keys(keyPrefix) {
+		raw, _ := t.cache.Get(key)
+		s, ok := raw.(Status)
+		if !ok || s.State == Offline || now.Sub(s.LastSeen) <= t.timeout {
+			continue
+		}
+		expired = append(expired, s)
+		s.State = Offline
+		s.RoomID = ""
+		t.cache.Set(key, s, t.retention)
+	}
+	t.mu.Unlock()
+
+	for _, s := range expired {
+		t.events.Publish(event.PresenceChanged, s.PlayerID, event.PresencePayload{From: s.State, To: Offline})
+	}
+}
+
+// update applies change to the stored record, stamps LastSeen unless the
+// player was and stays offline, and publishes event.PresenceChanged when the
+// state moves.
+func (t *Tracker) update(playerID string, change func(*Status)) Status {
+	now := time.Now()
+
+	t.mu.Lock()
+	s := t.load(playerID, now)
+	previous := s.State
+	change(&s)
+	if previous != Offline || s.State != Offline {
+		s.LastSeen = now
+	}
+	t.cache.Set(keyPrefix+playerID, s, t.retention)
+	t.mu.Unlock()
+
+	if s.State != previous {
+		t.events.Publish(event.PresenceChanged, playerID, event.PresencePayload{From: previous, To: s.State, RoomID: s.RoomID})
+	}
+	return s
+}
+
+func (t *Tracker) load(playerID string, now time.Time) Status {
+	raw, ok := t.cache.Get(keyPrefix + playerID)
+	if !ok {
+		return Status{PlayerID: playerID, State: Offline}
+	}
+	s := raw.(Status)
+	if s.State != Offline && now.Sub(s.LastSeen) > t.timeout {
+		s.State = Offline
+		s.RoomID = ""
+	}
+	return s
+}
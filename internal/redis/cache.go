@@ -1,6 +1,8 @@
 package redis
 
 import (
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -10,6 +12,12 @@ type entry struct {
 	expiresAt time.Time
 }
 
+// expired reports whether the entry is gone at now. Entries with a zero
+// expiry, such as sorted sets, never expire.
+func (e entry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
 // Cache provides an in-memory stand-in for Redis with expiration support.
 type Cache struct {
 	mu    sync.RWMutex
@@ -31,8 +39,44 @@ func (c *Cache) Get(key string) (interface{}, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	item, ok := c.items[key]
-	if !ok || time.Now().After(item.expiresAt) {
+	if !ok || item.expired(time.Now()) {
 		return nil, false
 	}
 	return item.value, true
 }
+
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.items, key)
+}
+
+// Keys returns the live keys starting with prefix, like SCAN MATCH prefix*.
+func (c *Cache) Keys(prefix string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	now := time.Now()
+	keys := make([]string, 0)
+	for key, item := range c.items {
+		if strings.HasPrefix(key, prefix) && !item.expired(now) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Incr increments the integer at key and pushes its expiry out to ttl from
+// now, so the counter lives as long as increments keep arriving.
+func (c *Cache) Incr(key string, ttl time.Duration) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	count := 0
+	if item, ok := c.items[key]; ok && !item.expired(now) {
+		count, _ = item.value.(int)
+	}
+	count++
+	c.items[key] = entry{value: count, expiresAt: now.Add(ttl)}
+	return count
+}
@@ -0,0 +1,155 @@
+package redis
+
+import "sort"
+
+// ZMember is a sorted-set member with its score.
+type ZMember struct {
+	Member string  `json:"member"`
+	Score  float64 `json:"score"`
+}
+
+// sortedSet keeps members ordered by score ascending, ties broken by member,
+// like a Redis ZSET.
+type sortedSet struct {
+	scores  map[string]float64
+	ordered []ZMember
+}
+
+func less(a, b ZMember) bool {
+	if a.Score != b.Score {
+		return a.Score < b.Score
+	}
+	return a.Member < b.Member
+}
+
+func (z *sortedSet) index(m ZMember) int {
+	return sort.Search(len(z.ordered), func(i int) bool { return !less(z.ordered[i], m) })
+}
+
+func (z *sortedSet) remove(member string) {
+	score, ok := z.scores[member]
+	if !ok {
+		return
+	}
+	i := z.index(ZMember{Member: member, Score: score})
+	z.ordered = append(z.ordered[:i], z.ordered[i+1:]...)
+	delete(z.scores, member)
+}
+
+func (z *sortedSet) add(member string, score float64) {
+	z.remove(member)
+	m := ZMember{Member: member, Score: score}
+	i := z.index(m)
+	z.ordered = append(z.ordered, ZMember{})
+	copy(z.ordered[i+1:], z.ordered[i:])
+	z.ordered[i] = m
+	z.scores[member] = score
+}
+
+// set returns the sorted set at key, creating it when create is set.
+// Sorted sets never expire. Callers must hold c.mu.
+func (c *Cache) set(key string, create bool) *sortedSet {
+	if item, ok := c.items[key]; ok {
+		if z, ok := item.value.(*sortedSet); ok {
+			return z
+		}
+	}
+	if !create {
+		return nil
+	}
+	z := &sortedSet{scores: map[string]float64{}}
+	c.items[key] = entry{value: z}
+	return z
+}
+
+// ZAdd sets member's score, like ZADD.
+func (c *Cache) ZAdd(key, member string, score float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(key, true).add(member, score)
+}
+
+// ZIncrBy adds delta to member's score, starting from zero, and returns the
+// new score, like ZINCRBY.
+func (c *Cache) ZIncrBy(key, member string, delta float64) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	z := c.set(key, true)
+	score := z.scores[member] + delta
+	z.add(member, score)
+	return score
+}
+
+// ZRem removes member, like ZREM.
+func (c *Cache) ZRem(key, member string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if z := c.set(key, false); z != nil {
+		z.remove(member)
+	}
+}
+
+// ZScore returns member's score, like ZSCORE.
+func (c *Cache) ZScore(key, member string) (float64, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	z := c.set(key, false)
+	if z == nil {
+		return 0, false
+	}
+	score, ok := z.scores[member]
+	return score, ok
+}
+
+// ZCard returns the number of members, like ZCARD.
+func (c *Cache) ZCard(key string) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if z := c.set(key, false); z != nil {
+		return len(z.ordered)
+	}
+	return 0
+}
+
+// ZRevRank returns member's zero-based position with the highest score
+// first, like ZREVRANK.
+func (c *Cache) ZRevRank(key, member string) (int, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	z := c.set(key, false)
+	if z == nil {
+		return 0, false
+	}
+	score, ok := z.scores[member]
+	if !ok {
+		return 0, false
+	}
+	return len(z.ordered) - 1 - z.index(ZMember{Member: member, Score: score}), true
+}
+
+// ZRevRange returns members from start to stop inclusive with the highest
+// score first, like ZREVRANGE WITHSCORES. Negative indexes count from the
+// end.
+func (c *Cache) ZRevRange(key string, start, stop int) []ZMember {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	members := make([]ZMember, 0)
+	z := c.set(key, false)
+	if z == nil {
+		return members
+	}
+
+	n := len(z.ordered)
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	start = max(start, 0)
+	stop = min(stop, n-1)
+	for i := start; i <= stop; i++ {
+		members = append(members, z.ordered[n-1-i])
+	}
+	return members
+}
@@ -0,0 +1,104 @@
+package redis
+
+import (
+	"reflect"
+	"testing"
+)
+
+// seed builds a set with a tie on 20 between b and c.
+func seed() *Cache {
+	c := NewCache()
+	c.ZAdd("board", "a", 10)
+	c.ZAdd("board", "b", 20)
+	c.ZAdd("board", "c", 20)
+	c.ZAdd("board", "d", 30)
+	return c
+}
+
+func TestZRevRank(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(c *Cache)
+		member string
+		rank   int
+		ok     bool
+	}{
+		{name: "highest", member: "d", rank: 0, ok: true},
+		{name: "lowest", member: "a", rank: 3, ok: true},
+		{name: "tie ranks later member first", member: "c", rank: 1, ok: true},
+		{name: "tie ranks earlier member second", member: "b", rank: 2, ok: true},
+		{name: "missing", member: "z", ok: false},
+		{name: "incr moves up", change: func(c *Cache) { c.ZIncrBy("board", "a", 15) }, member: "a", rank: 1, ok: true},
+		{name: "re-add replaces score", change: func(c *Cache) { c.ZAdd("board", "d", 0) }, member: "d", rank: 3, ok: true},
+		{name: "removed tie partner", change: func(c *Cache) { c.ZRem("board", "c") }, member: "b", rank: 1, ok: true},
+		{name: "removed member", change: func(c *Cache) { c.ZRem("board", "b") }, member: "b", ok: false},
+		{name: "remove keeps tie partner", change: func(c *Cache) { c.ZRem("board", "b") }, member: "c", rank: 1, ok: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := seed()
+			if tt.change != nil {
+				tt.change(c)
+			}
+			rank, ok := c.ZRevRank("board", tt.member)
+			if ok != tt.ok || rank != tt.rank {
+				t.Errorf("ZRevRank(%q) = %d, %v; want %d, %v", tt.member, rank, ok, tt.rank, tt.ok)
+			}
+		})
+	}
+}
+
+func TestZRemTies(t *testing.T) {
+	c := seed()
+	c.ZRem("board", "b")
+	c.ZRem("board", "missing")
+
+	if got := c.ZCard("board"); got != 3 {
+		t.Errorf("ZCard = %d, want 3", got)
+	}
+	if _, ok := c.ZScore("board", "b"); ok {
+		t.Error("ZScore found removed member")
+	}
+	if score, ok := c.ZScore("board", "c"); !ok || score != 20 {
+		t.Errorf("ZScore(c) = %v, %v; want 20, true", score, ok)
+	}
+	want := []ZMember{{"d", 30}, {"c", 20}, {"a", 10}}
+	if got := c.ZRevRange("board", 0, -1); !reflect.DeepEqual(got, want) {
+		t.Errorf("ZRevRange = %v, want %v", got, want)
+	}
+}
+
+func TestZRevRange(t *testing.T) {
+	tests := []struct {
+		name        string
+		start, stop int
+		want        []string
+	}{
+		{name: "all", start: 0, stop: -1, want: []string{"d", "c", "b", "a"}},
+		{name: "top two", start: 0, stop: 1, want: []string{"d", "c"}},
+		{name: "middle", start: 1, stop: 2, want: []string{"c", "b"}},
+		{name: "last two", start: -2, stop: -1, want: []string{"b", "a"}},
+		{name: "negative start only", start: -3, stop: 1, want: []string{"c"}},
+		{name: "start before beginning", start: -10, stop: 0, want: []string{"d"}},
+		{name: "stop past end", start: 2, stop: 10, want: []string{"b", "a"}},
+		{name: "start after stop", start: 2, stop: 1, want: []string{}},
+		{name: "start past end", start: 5, stop: -1, want: []string{}},
+		{name: "stop before beginning", start: 0, stop: -5, want: []string{}},
+	}
+	c := seed()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+			for _, m := range c.ZRevRange("board", tt.start, tt.stop) {
+				got = append(got, m.Member)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ZRevRange(%d, %d) = %v, want %v", tt.start, tt.stop, got, tt.want)
+			}
+		})
+	}
+
+	if got := c.ZRevRange("missing", 0, -1); got == nil || len(got) != 0 {
+		t.Errorf("ZRevRange on missing key = %#v, want empty", got)
+	}
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"goworld-skeleton/internal/auth"
+)
+
+// publicRoutes are served without a session token. Everything else requires
+// "Authorization: Bearer <token>".
+var publicRoutes = map[string]bool{
+	"/health":               true,
+	"/api/account/register": true,
+	"/api/account/login":    true,
+	"/api/account/guest":    true,
+
+	"/api/account/password/reset/request": true,
+	"/api/account/password/reset/confirm": true,
+
+	"/api/items":      true,
+	"/api/shop/items": true,
+	"/api/notice":     true,
+}
+
+// publicPrefixes are subtree routes, such as "/api/items/{id}", served
+// without a session token.
+var publicPrefixes = []string{
+	"/api/items/",
+	"/api/notice/",
+}
+
+func isPublic(path string) bool {
+	if publicRoutes[path] {
+		return true
+	}
+	for _, prefix := range publicPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// adminPrefix marks ops routes. They require "X-Admin-Token" matching the
+// configured admin token and an "X-Operator" header naming the ops user.
+const adminPrefix = "/api/admin/"
+
+// SessionVerifier validates bearer tokens against the session store.
+type SessionVerifier interface {
+	Verify(token string) (auth.Session, error)
+}
+
+func requireSession(sessions SessionVerifier, adminToken string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isPublic(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if strings.HasPrefix(r.URL.Path, adminPrefix) {
+			serveAdmin(adminToken, next, w, r)
+			return
+		}
+
+		token := auth.BearerToken(r)
+		if token == "" {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing bearer token"})
+			return
+		}
+
+		session, err := sessions.Verify(token)
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithSession(r.Context(), session)))
+	})
+}
+
+func serveAdmin(adminToken string, next http.Handler, w http.ResponseWriter, r *http.Request) {
+	given := r.Header.Get("X-Admin-Token")
+	if adminToken == "" || subtle.ConstantTimeCompare([]byte(given), []byte(adminToken)) != 1 {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "admin access denied"})
+		return
+	}
+
+	operator := strings.TrimSpace(r.Header.Get("X-Operator"))
+	if operator == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "X-Operator header required"})
+		return
+	}
+
+	next.ServeHTTP(w, r.WithContext(auth.WithOperator(r.Context(), operator)))
+}
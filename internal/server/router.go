@@ -7,19 +7,29 @@ import (
 
 // Services aggregates domain services for dependency injection.
 type Services struct {
-	Account AccountRoutes
-	Player  PlayerRoutes
-	Bag     BagRoutes
-	Item    ItemRoutes
-	Shop    ShopRoutes
-	Mail    MailRoutes
-	Notice  NoticeRoutes
-	Chat    ChatRoutes
-	Room    RoomRoutes
-	Match   MatchRoutes
+	Sessions   SessionVerifier
+	AdminToken string
+
+	Account     AccountRoutes
+	Player      PlayerRoutes
+	Bag         BagRoutes
+	Item        ItemRoutes
+	Shop        ShopRoutes
+	Mail        MailRoutes
+	Notice      NoticeRoutes
+	Chat        ChatRoutes
+	Room        RoomRoutes
+	Match       MatchRoutes
+	Wallet      WalletRoutes
+	Friend      FriendRoutes
+	Quest       QuestRoutes
+	Leaderboard LeaderboardRoutes
+	Trade       TradeRoutes
 }
 
-// NewRouter wires HTTP handlers for all modules.
+// NewRouter wires HTTP handlers for all modules behind the session
+// middleware; see publicRoutes for the endpoints that skip it. Routes under
+// /api/admin/ take the admin token instead of a player session.
 func NewRouter(services Services) http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) {
@@ -36,8 +46,13 @@ func NewRouter(services Services) http.Handler {
 	services.Chat.Register(mux)
 	services.Room.Register(mux)
 	services.Match.Register(mux)
+	services.Wallet.Register(mux)
+	services.Friend.Register(mux)
+	services.Quest.Register(mux)
+	services.Leaderboard.Register(mux)
+	services.Trade.Register(mux)
 
-	return mux
+	return requireSession(services.Sessions, services.AdminToken, mux)
 }
 
 // Shared helpers and interfaces for handlers.
@@ -51,6 +66,11 @@ type NoticeRoutes interface{ Register(*http.ServeMux) }
 type ChatRoutes interface{ Register(*http.ServeMux) }
 type RoomRoutes interface{ Register(*http.ServeMux) }
 type MatchRoutes interface{ Register(*http.ServeMux) }
+type WalletRoutes interface{ Register(*http.ServeMux) }
+type FriendRoutes interface{ Register(*http.ServeMux) }
+type QuestRoutes interface{ Register(*http.ServeMux) }
+type LeaderboardRoutes interface{ Register(*http.ServeMux) }
+type TradeRoutes interface{ Register(*http.ServeMux) }
 
 func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
 	w.Header().Set("Content-Type", "application/json")